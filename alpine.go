@@ -4,7 +4,10 @@
 package alpine
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"sync"
 
 	"github.com/ach968/alpine/internal"
 )
@@ -19,8 +22,83 @@ const (
 	// ModeInt uses simple delta: value[i] - value[i-1]
 	// Best for: Monotonically increasing/decreasing integers (timestamps, counters)
 	ModeInt Mode = 1
+
+	// ModeFloatXOR stores raw IEEE-754 bits XORed with the previous value.
+	// Chosen automatically for float64 data without decimal structure
+	ModeFloatXOR Mode = 2
+
+	// ModeFloatRD uses ALP-RD: dictionary-encoded left bits + bit-packed right bits.
+	// Chosen automatically for high-precision doubles, or forced with WithALPRD
+	ModeFloatRD Mode = 3
+
+	// ModeBlocked splits a long series into blocks, each with its own mode
+	ModeBlocked Mode = 4
+
+	// ModeTimeSeries pairs a timestamp column with a value column, produced by
+	// TimeSeriesEncoder
+	ModeTimeSeries Mode = 5
+
+	// ModeFrame holds named columns, produced by FrameEncoder
+	ModeFrame Mode = 6
+
+	// ModeIntFOR bit-packs int64 values as offsets from the block minimum.
+	// Chosen automatically for bounded integers without a trend, or forced
+	// with WithFOR
+	ModeIntFOR Mode = 7
+
+	// ModeIntPFOR is ModeInt with the residuals bit-packed at the width that
+	// minimizes the encoded size and the outliers patched. Selected with WithPFOR
+	ModeIntPFOR Mode = 8
+
+	// ModeFloatPFOR is ModeFloat with the residuals coded as in ModeIntPFOR
+	ModeFloatPFOR Mode = 9
+
+	// ModeIntFastLanes is ModeInt with the residuals bit-packed in the
+	// FastLanes interleaved 1024-value layout, which trades some size for
+	// branch-free decoding. Selected with WithFastLanes
+	ModeIntFastLanes Mode = 10
+
+	// ModeFloatFastLanes is ModeFloat with the residuals coded as in
+	// ModeIntFastLanes
+	ModeFloatFastLanes Mode = 11
 )
 
+// String returns the name of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeFloat:
+		return "float"
+	case ModeInt:
+		return "int"
+	case ModeFloatXOR:
+		return "float-xor"
+	case ModeFloatRD:
+		return "float-rd"
+	case ModeBlocked:
+		return "blocked"
+	case ModeTimeSeries:
+		return "timeseries"
+	case ModeFrame:
+		return "frame"
+	case ModeIntFOR:
+		return "int-for"
+	case ModeIntPFOR:
+		return "int-pfor"
+	case ModeFloatPFOR:
+		return "float-pfor"
+	case ModeIntFastLanes:
+		return "int-fastlanes"
+	case ModeFloatFastLanes:
+		return "float-fastlanes"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// DefaultBlockSize is the number of values per block used by the encoders.
+// Series no longer than one block are encoded as a single segment.
+const DefaultBlockSize = 1024
+
 // Options configures the encoding process
 type Options struct {
 	Mode        Mode // Encoding mode (default: ModeFloat for floats)
@@ -35,6 +113,11 @@ type FloatEncoder struct {
 	precision     int
 	autoRiceParam bool
 	autoPrecision bool
+	alprd         bool
+	residuals     residualCoding
+	blockSize     int
+	checksum      bool
+	valid         []bool
 }
 
 // NewFloatEncoder creates a new FloatEncoder with the given data
@@ -43,6 +126,7 @@ func NewFloatEncoder(data []float64) *FloatEncoder {
 		data:      data,
 		riceParam: 0,
 		precision: 0,
+		blockSize: DefaultBlockSize,
 	}
 }
 
@@ -73,10 +157,77 @@ func (e *FloatEncoder) WithAutoPrecision() *FloatEncoder {
 	return e
 }
 
+// WithALPRD forces the ALP-RD scheme, which splits each value's raw bits into a
+// dictionary-encoded left part and a bit-packed right part. Use it for doubles
+// without decimal structure; it is otherwise chosen automatically when the
+// decimal scheme does not fit the data
+func (e *FloatEncoder) WithALPRD() *FloatEncoder {
+	e.alprd = true
+	return e
+}
+
+// WithPFOR codes the residuals of the predictor with patched frame-of-reference
+// instead of Golomb-Rice: they are bit-packed at the width that minimizes the
+// encoded size and the outliers are patched in. Use it for mostly-small
+// residuals with spikes, such as latencies. Blocks coded this way cannot be
+// appended to in place by an Appender.
+func (e *FloatEncoder) WithPFOR() *FloatEncoder {
+	e.residuals = residualPFOR
+	return e
+}
+
+// WithFastLanes codes the residuals of the predictor in the FastLanes layout:
+// 1024 residuals at a time, bit-packed at the width of the largest one across
+// 16 interleaved lanes, so that decoding runs branch-free loops instead of
+// reading Golomb-Rice codes bit by bit. It decodes about twice as fast and is
+// usually somewhat larger. Blocks coded this way cannot be appended to in
+// place by an Appender.
+func (e *FloatEncoder) WithFastLanes() *FloatEncoder {
+	e.residuals = residualFastLanes
+	return e
+}
+
+// WithBlockSize sets the number of values per block (default DefaultBlockSize).
+// Each block picks its own precision and Rice parameter, so smaller blocks
+// follow drifting data more closely at the cost of a few header bytes each
+func (e *FloatEncoder) WithBlockSize(size int) *FloatEncoder {
+	e.blockSize = size
+	return e
+}
+
+// WithChecksum appends a CRC32C checksum over the header and payload, which
+// the decoder and Verify check before trusting the data
+func (e *FloatEncoder) WithChecksum() *FloatEncoder {
+	e.checksum = true
+	return e
+}
+
 // Encode compresses the float64 data and returns the encoded bytes
 func (e *FloatEncoder) Encode() ([]byte, error) {
-	if len(e.data) < 2 {
-		return nil, fmt.Errorf("input must have at least 2 elements, got %d", len(e.data))
+	data, validity, err := splitNulls(e.data, e.valid)
+	if err != nil {
+		return nil, err
+	}
+
+	header, payload, err := e.encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalBlob(header, withValidity(header, validity, payload), e.checksum), nil
+}
+
+// encode checks the options and encodes data, which holds no nulls, as a
+// single segment or a block section
+func (e *FloatEncoder) encode(data []float64) (*internal.Header, []byte, error) {
+	if err := checkBlockSize(e.blockSize); err != nil {
+		return nil, nil, err
+	}
+	if !e.autoRiceParam && e.riceParam > internal.MaxRiceParam {
+		return nil, nil, fmt.Errorf("rice parameter %d exceeds maximum %d", e.riceParam, internal.MaxRiceParam)
+	}
+	if !e.autoPrecision && e.precision > internal.MaxALPExponent {
+		return nil, nil, fmt.Errorf("precision %d exceeds maximum %d", e.precision, internal.MaxALPExponent)
 	}
 
 	riceParam := e.riceParam
@@ -88,17 +239,68 @@ func (e *FloatEncoder) Encode() ([]byte, error) {
 		exponent = -1
 	}
 
-	scaled, exp, err := internal.ALPEncode(e.data, exponent)
+	if riceParam <= 0 || e.autoRiceParam {
+		riceParam = 0
+	}
+
+	return encodeSeries(len(data), e.blockSize, func(start, end int) (*internal.Header, []byte, error) {
+		return e.encodeSegment(data[start:end], exponent, riceParam)
+	})
+}
+
+// encodeSegment picks the smallest float representation for data and returns
+// its header and payload
+func (e *FloatEncoder) encodeSegment(data []float64, exponent, riceParam int) (*internal.Header, []byte, error) {
+	// An empty series needs neither a Rice parameter nor a precision
+	if len(data) == 0 {
+		header, payload := encodeXOR(data)
+		return header, payload, nil
+	}
+
+	if e.alprd {
+		header, payload := encodeALPRD(data)
+		return header, payload, nil
+	}
+
+	header, payload, exceptionCount, err := encodeALP(data, exponent, riceParam, e.residuals)
+	if err != nil && !errors.Is(err, errALPUnprofitable) {
+		return nil, nil, err
+	}
+
+	// Exceptions keep ALP lossless, but when many values lack decimal
+	// structure one of the raw-bit representations is smaller. Below a quarter
+	// of the values, exceptions cost less than the raw bits of the rest.
+	if err != nil || exceptionCount > len(data)/4 {
+		for _, fallback := range []func([]float64) (*internal.Header, []byte){encodeALPRD, encodeXOR} {
+			h, p := fallback(data)
+			if header == nil || len(p) < len(payload) {
+				header, payload = h, p
+			}
+		}
+	}
+
+	return header, payload, nil
+}
+
+// errALPUnprofitable reports that the ALP residuals would take more space than
+// the raw values, so another representation should be used instead.
+var errALPUnprofitable = errors.New("alp residuals wider than raw values")
+
+// encodeALP runs the ALP pipeline and returns the header, the payload and the
+// number of exceptions. A riceParam of 0 selects one automatically for
+// Golomb-Rice coded residuals.
+func encodeALP(data []float64, exponent int, riceParam int, coding residualCoding) (*internal.Header, []byte, int, error) {
+	scaled, exp, factor, exceptions, err := internal.ALPEncode(data, exponent)
 	if err != nil {
-		return nil, fmt.Errorf("alp encode: %w", err)
+		return nil, nil, 0, fmt.Errorf("alp encode: %w", err)
 	}
 
 	deltas, first, second, err := internal.DeltaEncode(scaled)
 	if err != nil {
-		return nil, fmt.Errorf("delta encode: %w", err)
+		return nil, nil, 0, fmt.Errorf("delta encode: %w", err)
 	}
 
-	if riceParam <= 0 || e.autoRiceParam {
+	if riceParam <= 0 {
 		riceParam = internal.AutoRiceParam(deltas)
 	}
 
@@ -106,39 +308,148 @@ func (e *FloatEncoder) Encode() ([]byte, error) {
 	if len(deltas) > 0 {
 		zigzagged, err = internal.ZigZagEncode(deltas)
 		if err != nil {
-			return nil, fmt.Errorf("zigzag encode: %w", err)
+			return nil, nil, 0, fmt.Errorf("zigzag encode: %w", err)
 		}
 	}
 
-	var packed internal.PackedData
-	if len(zigzagged) > 0 {
-		packed, err = internal.GolombRiceEncode(zigzagged, riceParam)
-		if err != nil {
-			return nil, fmt.Errorf("golomb-rice encode: %w", err)
-		}
+	// Residuals wider than the Rice parameter are escaped at a premium; bail
+	// out before materializing a stream larger than the raw data
+	if len(zigzagged) > 0 && residualSize(zigzagged, riceParam, coding) > 64*uint64(len(zigzagged)) {
+		return nil, nil, 0, errALPUnprofitable
+	}
+
+	residuals, err := packResiduals(zigzagged, riceParam, coding)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	header := &internal.Header{
 		Mode:       internal.ModeFloat,
 		RiceParam:  riceParam,
 		ALPExp:     exp,
+		ALPFactor:  factor,
 		First:      first,
 		Second:     second,
-		ValueCount: len(e.data),
+		ValueCount: len(data),
+	}
+	if coding != residualRice {
+		header.Mode, header.RiceParam = coding.mode(internal.ModeFloat), 0
 	}
 
-	output := make([]byte, internal.HeaderSize+len(packed.Data))
-	copy(output, header.Marshal())
-	copy(output[internal.HeaderSize:], packed.Data)
+	// Values that did not survive scaling follow the residuals verbatim
+	payload := append(residuals, internal.MarshalExceptions(exceptions)...)
 
-	return output, nil
+	return header, payload, len(exceptions), nil
+}
+
+// encodeXOR stores the raw bits of each value XORed with its predecessor
+func encodeXOR(data []float64) (*internal.Header, []byte) {
+	header := &internal.Header{
+		Mode:       internal.ModeFloatXOR,
+		ValueCount: len(data),
+	}
+	if len(data) > 0 {
+		header.First = int64(math.Float64bits(data[0]))
+	}
+	return header, internal.XOREncode(data)
+}
+
+// encodeALPRD splits each value into a dictionary-encoded left part and a
+// bit-packed right part
+func encodeALPRD(data []float64) (*internal.Header, []byte) {
+	header := &internal.Header{
+		Mode:       internal.ModeFloatRD,
+		ValueCount: len(data),
+	}
+	return header, internal.ALPRDEncode(data)
+}
+
+// encodeSeries encodes a series of n values as a single segment when it fits
+// in one block, and otherwise splits it into blocks of size values, encodes
+// each with encode and wraps them in a ModeBlocked section
+func encodeSeries(n, size int, encode func(start, end int) (*internal.Header, []byte, error)) (*internal.Header, []byte, error) {
+	if n <= size {
+		return encode(0, n)
+	}
+
+	headers, payloads, err := encodeBlocks(n, size, encode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := &internal.Header{
+		Mode:       internal.ModeBlocked,
+		ValueCount: n,
+	}
+
+	return header, internal.MarshalBlocks(headers, payloads), nil
+}
+
+// checkBlockSize reports whether size is a usable block size. A block may take
+// one value more than size, so that no block is left without its two seeds.
+func checkBlockSize(size int) error {
+	if size < 2 || size >= internal.MaxSegmentValues {
+		return fmt.Errorf("block size must be between 2 and %d, got %d", internal.MaxSegmentValues-1, size)
+	}
+	return nil
+}
+
+// encodeBlocks splits a series of n values into blocks of size values and
+// encodes each with encode. A series no longer than one block is one block.
+func encodeBlocks(n, size int, encode func(start, end int) (*internal.Header, []byte, error)) ([]*internal.Header, [][]byte, error) {
+	var headers []*internal.Header
+	var payloads [][]byte
+	for start := 0; start < n; {
+		end := min(start+size, n)
+		// Every block needs its two seed values; a shorter tail joins this block
+		if n-end < 2 {
+			end = n
+		}
+
+		header, payload, err := encode(start, end)
+		if err != nil {
+			return nil, nil, fmt.Errorf("block %d: %w", len(headers), err)
+		}
+		headers = append(headers, header)
+		payloads = append(payloads, payload)
+		start = end
+	}
+
+	return headers, payloads, nil
+}
+
+// marshalBlob prepends the marshaled header to payload and, if requested,
+// appends a checksum over both. The compact header is used whenever it is
+// smaller than the full one, which is nearly always for short series.
+func marshalBlob(header *internal.Header, payload []byte, checksum bool) []byte {
+	if checksum {
+		header.Flags |= internal.FlagChecksum
+	}
+
+	headerBytes := header.Marshal()
+	if compact := header.MarshalCompact(); compact != nil && len(compact) < len(headerBytes) {
+		headerBytes = compact
+	}
+
+	output := make([]byte, 0, len(headerBytes)+len(payload)+internal.ChecksumSize)
+	output = append(output, headerBytes...)
+	output = append(output, payload...)
+	if checksum {
+		output = internal.AppendChecksum(output)
+	}
+	return output
 }
 
 // IntEncoder is a builder for encoding int64 data
 type IntEncoder struct {
-	data          []int64
-	riceParam     int
-	autoRiceParam bool
+	data             []int64
+	riceParam        int
+	autoRiceParam    bool
+	frameOfReference bool
+	residuals        residualCoding
+	blockSize        int
+	checksum         bool
+	valid            []bool
 }
 
 // NewIntEncoder creates a new IntEncoder with the given data
@@ -146,6 +457,7 @@ func NewIntEncoder(data []int64) *IntEncoder {
 	return &IntEncoder{
 		data:      data,
 		riceParam: 0,
+		blockSize: DefaultBlockSize,
 	}
 }
 
@@ -162,36 +474,124 @@ func (e *IntEncoder) WithAutoRiceParam() *IntEncoder {
 	return e
 }
 
+// WithFOR forces frame-of-reference bit-packing, which stores each value as
+// its offset from the block minimum at the bit width of max-min. It is
+// otherwise chosen automatically, when the Rice parameter is, for blocks it
+// codes smaller than the predictive delta pipeline
+func (e *IntEncoder) WithFOR() *IntEncoder {
+	e.frameOfReference = true
+	return e
+}
+
+// WithPFOR codes the residuals of the predictor with patched frame-of-reference
+// instead of Golomb-Rice: they are bit-packed at the width that minimizes the
+// encoded size and the outliers are patched in. Use it for mostly-small
+// residuals with spikes, such as latencies. Blocks coded this way cannot be
+// appended to in place by an Appender.
+func (e *IntEncoder) WithPFOR() *IntEncoder {
+	e.residuals = residualPFOR
+	return e
+}
+
+// WithFastLanes codes the residuals of the predictor in the FastLanes layout:
+// 1024 residuals at a time, bit-packed at the width of the largest one across
+// 16 interleaved lanes, so that decoding runs branch-free loops instead of
+// reading Golomb-Rice codes bit by bit. It decodes about twice as fast and is
+// usually somewhat larger. Blocks coded this way cannot be appended to in
+// place by an Appender.
+func (e *IntEncoder) WithFastLanes() *IntEncoder {
+	e.residuals = residualFastLanes
+	return e
+}
+
+// WithBlockSize sets the number of values per block (default DefaultBlockSize).
+// Each block picks its own Rice parameter
+func (e *IntEncoder) WithBlockSize(size int) *IntEncoder {
+	e.blockSize = size
+	return e
+}
+
+// WithChecksum appends a CRC32C checksum over the header and payload, which
+// the decoder and Verify check before trusting the data
+func (e *IntEncoder) WithChecksum() *IntEncoder {
+	e.checksum = true
+	return e
+}
+
 // Encode compresses the int64 data using predictive delta encoding and returns the encoded bytes
 func (e *IntEncoder) Encode() ([]byte, error) {
-	if len(e.data) < 2 {
-		return nil, fmt.Errorf("input must have at least 2 elements, got %d", len(e.data))
+	data, validity, err := splitNulls(e.data, e.valid)
+	if err != nil {
+		return nil, err
+	}
+
+	header, payload, err := e.encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalBlob(header, withValidity(header, validity, payload), e.checksum), nil
+}
+
+// encode checks the options and encodes data, which holds no nulls, as a
+// single segment or a block section
+func (e *IntEncoder) encode(data []int64) (*internal.Header, []byte, error) {
+	if err := checkBlockSize(e.blockSize); err != nil {
+		return nil, nil, err
+	}
+	if !e.autoRiceParam && e.riceParam > internal.MaxRiceParam {
+		return nil, nil, fmt.Errorf("rice parameter %d exceeds maximum %d", e.riceParam, internal.MaxRiceParam)
 	}
 
 	riceParam := e.riceParam
 	if riceParam <= 0 || e.autoRiceParam {
-		riceParam = internal.AutoRiceParam(e.data)
+		riceParam = 0
 	}
 
-	deltas, first, second, err := internal.DeltaEncode(e.data)
+	return encodeSeries(len(data), e.blockSize, func(start, end int) (*internal.Header, []byte, error) {
+		if e.frameOfReference {
+			header, payload := encodeFOR(data[start:end])
+			return header, payload, nil
+		}
+		return encodeInt(data[start:end], riceParam, e.residuals)
+	})
+}
+
+// encodeInt runs the predictive delta pipeline and returns the header and the
+// payload. A riceParam of 0 selects one automatically for Golomb-Rice coded
+// residuals, and switches to frame-of-reference bit-packing when that is
+// smaller.
+func encodeInt(data []int64, riceParam int, coding residualCoding) (*internal.Header, []byte, error) {
+	deltas, first, second, err := internal.DeltaEncode(data)
 	if err != nil {
-		return nil, fmt.Errorf("delta encode: %w", err)
+		return nil, nil, fmt.Errorf("delta encode: %w", err)
+	}
+
+	// The parameter is tuned to the residuals that are actually coded
+	auto := riceParam <= 0
+	if auto {
+		riceParam = internal.AutoRiceParam(deltas)
 	}
 
 	var zigzagged []uint64
 	if len(deltas) > 0 {
 		zigzagged, err = internal.ZigZagEncode(deltas)
 		if err != nil {
-			return nil, fmt.Errorf("zigzag encode: %w", err)
+			return nil, nil, fmt.Errorf("zigzag encode: %w", err)
 		}
 	}
 
-	var packed internal.PackedData
-	if len(zigzagged) > 0 {
-		packed, err = internal.GolombRiceEncode(zigzagged, riceParam)
-		if err != nil {
-			return nil, fmt.Errorf("golomb-rice encode: %w", err)
-		}
+	// The predictor turns bounded values that jump around into residuals
+	// twice as wide as the values themselves. FastLanes is picked for decode
+	// speed, which bit-packing at a smaller size would not match.
+	if auto && coding != residualFastLanes && len(zigzagged) > 0 && internal.FORSize(data) < residualSize(zigzagged, riceParam, coding) {
+		header, payload := encodeFOR(data)
+		return header, payload, nil
+	}
+
+	payload, err := packResiduals(zigzagged, riceParam, coding)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	header := &internal.Header{
@@ -200,251 +600,490 @@ func (e *IntEncoder) Encode() ([]byte, error) {
 		ALPExp:     0,
 		First:      first,
 		Second:     second,
-		ValueCount: len(e.data),
+		ValueCount: len(data),
+	}
+	if coding != residualRice {
+		header.Mode, header.RiceParam = coding.mode(internal.ModeInt), 0
 	}
 
-	output := make([]byte, internal.HeaderSize+len(packed.Data))
-	copy(output, header.Marshal())
-	copy(output[internal.HeaderSize:], packed.Data)
-
-	return output, nil
+	return header, payload, nil
 }
 
-// Decoder is a builder for decoding compressed data
-type Decoder struct {
-	encoded []byte
-}
+// residualCoding selects how the zigzagged residuals of the predictor are
+// coded
+type residualCoding int
 
-// NewDecoder creates a new Decoder with the given encoded data
-func NewDecoder(encoded []byte) *Decoder {
-	return &Decoder{
-		encoded: encoded,
+const (
+	residualRice residualCoding = iota
+	residualPFOR
+	residualFastLanes
+)
+
+// mode returns the mode of the ModeInt or ModeFloat pipeline with its
+// residuals coded by c
+func (c residualCoding) mode(base internal.Mode) internal.Mode {
+	switch {
+	case c == residualPFOR && base == internal.ModeInt:
+		return internal.ModeIntPFOR
+	case c == residualPFOR:
+		return internal.ModeFloatPFOR
+	case c == residualFastLanes && base == internal.ModeInt:
+		return internal.ModeIntFastLanes
+	case c == residualFastLanes:
+		return internal.ModeFloatFastLanes
+	default:
+		return base
 	}
 }
 
-// DecodeFloat decodes the encoded data as float64 values
-func (d *Decoder) DecodeFloat() ([]float64, error) {
-	if len(d.encoded) < internal.HeaderSize {
-		return nil, fmt.Errorf("data too short: need at least %d bytes, got %d", internal.HeaderSize, len(d.encoded))
+// residualSize returns the size in bits of zigzagged residuals coded by
+// coding, with riceParam for Golomb-Rice
+func residualSize(zigzagged []uint64, riceParam int, coding residualCoding) uint64 {
+	switch coding {
+	case residualPFOR:
+		return internal.PFORSize(zigzagged)
+	case residualFastLanes:
+		return internal.FastLanesSize(zigzagged)
+	default:
+		return internal.GolombRiceSize(zigzagged, riceParam)
 	}
+}
 
-	header, err := internal.Unmarshal(d.encoded)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal header: %w", err)
+// packResiduals codes zigzagged residuals by coding, with riceParam for
+// Golomb-Rice
+func packResiduals(zigzagged []uint64, riceParam int, coding residualCoding) ([]byte, error) {
+	if len(zigzagged) == 0 {
+		return nil, nil
 	}
-
-	if err := header.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid header: %w", err)
+	switch coding {
+	case residualPFOR:
+		return internal.PFOREncode(zigzagged), nil
+	case residualFastLanes:
+		return internal.FastLanesEncode(zigzagged), nil
 	}
 
-	if header.Mode != internal.ModeFloat {
-		return nil, fmt.Errorf("expected ModeFloat, got %v", header.Mode)
+	packed, err := internal.GolombRiceEncode(zigzagged, riceParam)
+	if err != nil {
+		return nil, fmt.Errorf("golomb-rice encode: %w", err)
 	}
+	return packed.Data, nil
+}
 
-	payload := d.encoded[internal.HeaderSize:]
-	deltaCount := header.ValueCount - 2
+// unpackResiduals reverses packResiduals for count residuals at the start of
+// payload and returns the number of bytes they take
+func unpackResiduals(header *internal.Header, payload []byte, count int) ([]uint64, int, error) {
+	if count <= 0 {
+		return nil, 0, nil
+	}
 
-	var zigzagged []uint64
-	if deltaCount > 0 {
-		var err error
-		zigzagged, err = internal.GolombRiceDecode(payload, 0, deltaCount, header.RiceParam)
+	switch {
+	case header.Mode.UsesPFOR():
+		zigzagged, n, err := internal.PFORDecode(payload, count)
 		if err != nil {
-			return nil, fmt.Errorf("golomb-rice decode: %w", err)
+			return nil, 0, fmt.Errorf("pfor decode: %w", err)
 		}
-	}
-
-	var deltas []int64
-	if len(zigzagged) > 0 {
-		var err error
-		deltas, err = internal.ZigZagDecode(zigzagged)
+		return zigzagged, n, nil
+	case header.Mode.UsesFastLanes():
+		zigzagged, n, err := internal.FastLanesDecode(payload, count)
 		if err != nil {
-			return nil, fmt.Errorf("zigzag decode: %w", err)
+			return nil, 0, fmt.Errorf("fastlanes decode: %w", err)
 		}
+		return zigzagged, n, nil
 	}
 
-	scaled, err := internal.DeltaDecode(deltas, header.First, header.Second)
+	zigzagged, n, err := internal.GolombRiceDecodeN(payload, count, header.RiceParam)
 	if err != nil {
-		return nil, fmt.Errorf("delta decode: %w", err)
+		return nil, 0, fmt.Errorf("golomb-rice decode: %w", err)
 	}
+	return zigzagged, n, nil
+}
 
-	result := internal.ALPDecode(scaled, header.ALPExp)
+// encodeFOR bit-packs data as offsets from its minimum, which the header
+// stores as the first seed value
+func encodeFOR(data []int64) (*internal.Header, []byte) {
+	reference, payload := internal.FOREncode(data)
+	header := &internal.Header{
+		Mode:       internal.ModeIntFOR,
+		First:      reference,
+		ValueCount: len(data),
+	}
+	return header, payload
+}
 
-	return result, nil
+// Decoder is a builder for decoding compressed data. It is safe for concurrent
+// use.
+type Decoder struct {
+	encoded []byte // the blob, or the column payload of a column decoder
+
+	// Parsed on first use and reused by point lookups. The Once fields make the
+	// caches safe to fill from concurrent calls sharing one Decoder.
+	indexOnce sync.Once
+	indexErr  error
+	header    *internal.Header
+	blocks    []internal.Block
+	valid     []bool // nil unless the blob has FlagNullable
+
+	columnsOnce sync.Once
+	columnsErr  error
+	columns     []*Decoder // ModeTimeSeries and ModeFrame only
+	names       []string   // ModeFrame only
 }
 
-// DecodeInt decodes the encoded data as int64 values
-func (d *Decoder) DecodeInt() ([]int64, error) {
-	if len(d.encoded) < internal.HeaderSize {
-		return nil, fmt.Errorf("data too short: need at least %d bytes, got %d", internal.HeaderSize, len(d.encoded))
+// NewDecoder creates a new Decoder with the given encoded data
+func NewDecoder(encoded []byte) *Decoder {
+	return &Decoder{
+		encoded: encoded,
 	}
+}
 
-	header, err := internal.Unmarshal(d.encoded)
+// readHeader parses and validates the header, checks the checksum if the blob
+// carries one, and returns the header with the payload
+func (d *Decoder) readHeader() (*internal.Header, []byte, error) {
+	header, size, err := internal.Unmarshal(d.encoded)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal header: %w", err)
+		return nil, nil, fmt.Errorf("unmarshal header: %w", err)
 	}
 
 	if err := header.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid header: %w", err)
+		return nil, nil, fmt.Errorf("invalid header: %w", err)
 	}
 
-	if header.Mode != internal.ModeInt {
-		return nil, fmt.Errorf("expected ModeInt, got %v", header.Mode)
+	body := d.encoded
+	if header.Flags&internal.FlagChecksum != 0 {
+		body, err = internal.VerifyChecksum(d.encoded)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(body) < size {
+			return nil, nil, errors.New("checksum trailer overlaps header")
+		}
 	}
 
-	payload := d.encoded[internal.HeaderSize:]
-	deltaCount := header.ValueCount - 2
+	return header, body[size:], nil
+}
 
-	var zigzagged []uint64
-	if deltaCount > 0 {
-		var err error
-		zigzagged, err = internal.GolombRiceDecode(payload, 0, deltaCount, header.RiceParam)
-		if err != nil {
-			return nil, fmt.Errorf("golomb-rice decode: %w", err)
+// blockIndex parses the header and block index once and caches them. A
+// single-segment blob is reported as one block spanning the whole payload.
+func (d *Decoder) blockIndex() (*internal.Header, []internal.Block, error) {
+	d.indexOnce.Do(func() {
+		// Column decoders are created with their index already parsed
+		if d.header == nil {
+			d.indexErr = d.parseIndex()
 		}
+	})
+	if d.indexErr != nil {
+		return nil, nil, d.indexErr
 	}
+	return d.header, d.blocks, nil
+}
 
-	var deltas []int64
-	if len(zigzagged) > 0 {
-		var err error
-		deltas, err = internal.ZigZagDecode(zigzagged)
+// parseIndex fills the caches of blockIndex
+func (d *Decoder) parseIndex() error {
+	header, payload, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+
+	var valid []bool
+	if header.Flags&internal.FlagNullable != 0 {
+		var n int
+		valid, n, err = internal.UnmarshalValidity(payload, header.ValueCount)
 		if err != nil {
-			return nil, fmt.Errorf("zigzag decode: %w", err)
+			return err
 		}
+		payload = payload[n:]
 	}
 
-	result, err := internal.DeltaDecode(deltas, header.First, header.Second)
+	blocks, err := segmentBlocks(header, payload)
 	if err != nil {
-		return nil, fmt.Errorf("delta decode: %w", err)
+		return err
 	}
 
-	return result, nil
+	d.header, d.blocks, d.valid = header, blocks, valid
+	return nil
 }
 
-// Encode compresses float64 data using the specified mode.
-// For ModeFloat, uses ALP + Predictive Delta encoding (lossless).
-func Encode(input []float64, opts Options) ([]byte, error) {
-	if len(input) < 2 {
-		return nil, fmt.Errorf("input must have at least 2 elements, got %d", len(input))
+// segmentBlocks parses the block index of a ModeBlocked payload, or reports
+// any other payload as one block spanning all of it. Either way every block's
+// value count is checked against its payload.
+func segmentBlocks(header *internal.Header, payload []byte) ([]internal.Block, error) {
+	if header.Mode != internal.ModeBlocked {
+		if err := header.CheckPayload(payload); err != nil {
+			return nil, err
+		}
+		return []internal.Block{{Header: header, Payload: payload}}, nil
 	}
 
-	if opts.Mode == 0 {
-		opts.Mode = ModeFloat // Default mode for floats
+	blocks, err := internal.UnmarshalBlocks(payload, header.ValueCount)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal blocks: %w", err)
 	}
+	return blocks, nil
+}
 
-	if opts.ALPExponent == 0 {
-		opts.ALPExponent = -1 // Default: auto-detect precision
+// denseIndex is blockIndex for the APIs that return values without their
+// validity, which cannot represent a series with nulls
+func (d *Decoder) denseIndex() (*internal.Header, []internal.Block, error) {
+	header, blocks, err := d.blockIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.valid != nil {
+		return nil, nil, errNullable
 	}
+	return header, blocks, nil
+}
 
-	if opts.Mode != ModeFloat {
-		return nil, fmt.Errorf("mode %v not supported for float64, use ModeFloat", opts.Mode)
+// blockError identifies the failing block of a blocked blob
+func blockError(header *internal.Header, i int, err error) error {
+	if header.Mode != internal.ModeBlocked {
+		return err
 	}
+	return fmt.Errorf("block %d: %w", i, err)
+}
+
+// checkIndex reports whether i addresses a value of the series
+func checkIndex(header *internal.Header, i int) error {
+	if i < 0 || i >= header.ValueCount {
+		return fmt.Errorf("index %d out of range [0, %d)", i, header.ValueCount)
+	}
+	return nil
+}
 
-	// Step 1: ALP encoding
-	scaled, exponent, err := internal.ALPEncode(input, opts.ALPExponent)
+// decodeRange decodes values [start, end) with decode, touching only the
+// blocks that overlap the window
+func decodeRange[T any](d *Decoder, start, end int, decode func(*internal.Header, []byte) ([]T, error)) ([]T, error) {
+	header, blocks, err := d.blockIndex()
 	if err != nil {
-		return nil, fmt.Errorf("alp encode: %w", err)
+		return nil, err
+	}
+	if start < 0 || end < start || end > header.ValueCount {
+		return nil, fmt.Errorf("range [%d, %d) out of bounds [0, %d)", start, end, header.ValueCount)
 	}
 
-	// Step 2: Predictive delta encoding
-	deltas, first, second, err := internal.DeltaEncode(scaled)
+	// The block holding start is decoded even for an empty window, so reading
+	// a blob as the wrong kind fails consistently, including empty series
+	first := min(internal.FindBlock(blocks, start), len(blocks)-1)
+
+	var result []T
+	for b := first; b < len(blocks) && (b == first || blocks[b].Start < end); b++ {
+		values, err := decode(blocks[b].Header, blocks[b].Payload)
+		if err != nil {
+			return nil, blockError(header, b, err)
+		}
+		if result == nil {
+			// Sized from a decoded block rather than the header count alone,
+			// which a corrupt blob could inflate
+			size := end - start
+			if n := len(blocks) - first; len(values) < size/n {
+				size = len(values) * n
+			}
+			result = make([]T, 0, size)
+		}
+		lo := max(start-blocks[b].Start, 0)
+		hi := min(end-blocks[b].Start, len(values))
+		result = append(result, values[lo:hi]...)
+	}
+
+	return result, nil
+}
+
+// DecodeFloat decodes the encoded data as float64 values
+func (d *Decoder) DecodeFloat() ([]float64, error) {
+	header, _, err := d.denseIndex()
 	if err != nil {
-		return nil, fmt.Errorf("delta encode: %w", err)
+		return nil, err
 	}
+	return decodeRange(d, 0, header.ValueCount, decodeFloatSegment)
+}
 
-	// Step 3: Determine Rice parameter
-	riceParam := opts.RiceParam
-	if riceParam <= 0 {
-		riceParam = internal.AutoRiceParam(deltas)
+// DecodeFloatRange decodes the float64 values with indices in [start, end).
+// Blocks outside the window are skipped without being decoded.
+func (d *Decoder) DecodeFloatRange(start, end int) ([]float64, error) {
+	if _, _, err := d.denseIndex(); err != nil {
+		return nil, err
 	}
+	return decodeRange(d, start, end, decodeFloatSegment)
+}
 
-	// Step 4: ZigZag encoding (skip if no deltas)
-	var zigzagged []uint64
-	if len(deltas) > 0 {
-		zigzagged, err = internal.ZigZagEncode(deltas)
+// At returns the float64 value at index i. Only the block containing the
+// value is decoded, and the block index is parsed once per Decoder.
+func (d *Decoder) At(i int) (float64, error) {
+	header, _, err := d.denseIndex()
+	if err != nil {
+		return 0, err
+	}
+	if err := checkIndex(header, i); err != nil {
+		return 0, err
+	}
+
+	values, err := decodeRange(d, i, i+1, decodeFloatSegment)
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// decodeFloatSegment decodes a single-segment payload of a float mode
+func decodeFloatSegment(header *internal.Header, payload []byte) ([]float64, error) {
+	switch header.Mode {
+	case internal.ModeFloat, internal.ModeFloatPFOR, internal.ModeFloatFastLanes:
+		return decodeALP(header, payload)
+	case internal.ModeFloatXOR:
+		result, err := internal.XORDecode(payload, uint64(header.First), header.ValueCount)
 		if err != nil {
-			return nil, fmt.Errorf("zigzag encode: %w", err)
+			return nil, fmt.Errorf("xor decode: %w", err)
 		}
+		return result, nil
+	case internal.ModeFloatRD:
+		result, err := internal.ALPRDDecode(payload, header.ValueCount)
+		if err != nil {
+			return nil, fmt.Errorf("alp-rd decode: %w", err)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected ModeFloat, got %v", header.Mode)
+	}
+}
+
+// decodeALP reverses encodeALP
+func decodeALP(header *internal.Header, payload []byte) ([]float64, error) {
+	zigzagged, consumed, err := unpackResiduals(header, payload, header.ValueCount-2)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions, _, err := internal.UnmarshalExceptions(payload[consumed:], header.ValueCount)
+	if err != nil {
+		return nil, fmt.Errorf("exceptions: %w", err)
 	}
 
-	// Step 5: Golomb-Rice encoding (skip if no zigzagged values)
-	var packed internal.PackedData
+	var deltas []int64
 	if len(zigzagged) > 0 {
-		packed, err = internal.GolombRiceEncode(zigzagged, riceParam)
+		deltas, err = internal.ZigZagDecode(zigzagged)
 		if err != nil {
-			return nil, fmt.Errorf("golomb-rice encode: %w", err)
+			return nil, fmt.Errorf("zigzag decode: %w", err)
 		}
 	}
 
-	// Step 6: Build header
-	header := &internal.Header{
-		Mode:       internal.Mode(opts.Mode),
-		RiceParam:  riceParam,
-		ALPExp:     exponent,
-		First:      first,
-		Second:     second,
-		ValueCount: len(input),
+	scaled, err := internal.DeltaDecode(deltas, header.First, header.Second)
+	if err != nil {
+		return nil, fmt.Errorf("delta decode: %w", err)
 	}
 
-	// Combine header and payload
-	output := make([]byte, internal.HeaderSize+len(packed.Data))
-	copy(output, header.Marshal())
-	copy(output[internal.HeaderSize:], packed.Data)
+	result := internal.ALPDecode(scaled[:header.ValueCount], header.ALPExp, header.ALPFactor)
+	internal.PatchExceptions(result, exceptions)
 
-	return output, nil
+	return result, nil
 }
 
-// Decode decompresses data produced by Encode (float64).
-func Decode(encoded []byte) ([]float64, error) {
-	if len(encoded) < internal.HeaderSize {
-		return nil, fmt.Errorf("data too short: need at least %d bytes, got %d", internal.HeaderSize, len(encoded))
-	}
-
-	header, err := internal.Unmarshal(encoded)
+// DecodeInt decodes the encoded data as int64 values
+func (d *Decoder) DecodeInt() ([]int64, error) {
+	header, _, err := d.denseIndex()
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal header: %w", err)
+		return nil, err
 	}
+	return decodeRange(d, 0, header.ValueCount, decodeIntSegment)
+}
 
-	if err := header.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid header: %w", err)
+// DecodeIntRange decodes the int64 values with indices in [start, end).
+// Blocks outside the window are skipped without being decoded.
+func (d *Decoder) DecodeIntRange(start, end int) ([]int64, error) {
+	if _, _, err := d.denseIndex(); err != nil {
+		return nil, err
 	}
+	return decodeRange(d, start, end, decodeIntSegment)
+}
 
-	if header.Mode != internal.ModeFloat {
-		return nil, fmt.Errorf("expected ModeFloat, got %v", header.Mode)
+// IntAt returns the int64 value at index i, decoding only the block that
+// contains it
+func (d *Decoder) IntAt(i int) (int64, error) {
+	header, _, err := d.denseIndex()
+	if err != nil {
+		return 0, err
+	}
+	if err := checkIndex(header, i); err != nil {
+		return 0, err
 	}
 
-	payload := encoded[internal.HeaderSize:]
-	deltaCount := header.ValueCount - 2
+	values, err := decodeRange(d, i, i+1, decodeIntSegment)
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
 
-	// Decode Golomb-Rice (skip if no deltas)
-	var zigzagged []uint64
-	if deltaCount > 0 {
-		var err error
-		zigzagged, err = internal.GolombRiceDecode(payload, 0, deltaCount, header.RiceParam)
+// decodeIntSegment decodes a single-segment ModeInt, ModeIntFOR, ModeIntPFOR
+// or ModeIntFastLanes payload
+func decodeIntSegment(header *internal.Header, payload []byte) ([]int64, error) {
+	if header.Mode == internal.ModeIntFOR {
+		values, err := internal.FORDecode(payload, header.First, header.ValueCount)
 		if err != nil {
-			return nil, fmt.Errorf("golomb-rice decode: %w", err)
+			return nil, fmt.Errorf("frame-of-reference decode: %w", err)
 		}
+		return values, nil
+	}
+	if header.Mode != internal.ModeInt && header.Mode != internal.ModeIntPFOR && header.Mode != internal.ModeIntFastLanes {
+		return nil, fmt.Errorf("expected ModeInt, got %v", header.Mode)
+	}
+
+	zigzagged, _, err := unpackResiduals(header, payload, header.ValueCount-2)
+	if err != nil {
+		return nil, err
 	}
 
-	// Decode ZigZag (skip if empty)
 	var deltas []int64
 	if len(zigzagged) > 0 {
-		var err error
 		deltas, err = internal.ZigZagDecode(zigzagged)
 		if err != nil {
 			return nil, fmt.Errorf("zigzag decode: %w", err)
 		}
 	}
 
-	// Decode predictive delta
-	scaled, err := internal.DeltaDecode(deltas, header.First, header.Second)
+	result, err := internal.DeltaDecode(deltas, header.First, header.Second)
 	if err != nil {
 		return nil, fmt.Errorf("delta decode: %w", err)
 	}
 
-	// ALP decode
-	result := internal.ALPDecode(scaled, header.ALPExp)
+	// Series shorter than two values only use part of the seeds
+	return result[:header.ValueCount], nil
+}
 
-	return result, nil
+// Verify checks the integrity of an encoded blob without decoding its values.
+// The header and block index (and column directory) are always validated,
+// including that each payload can hold its value count; blobs encoded with
+// WithChecksum additionally have their checksum checked, which detects
+// corruption anywhere in the payload.
+func Verify(encoded []byte) error {
+	d := NewDecoder(encoded)
+	header, _, err := d.blockIndex()
+	if err != nil {
+		return err
+	}
+	if header.Mode == internal.ModeTimeSeries || header.Mode == internal.ModeFrame {
+		_, _, err = d.columnIndex()
+	}
+	return err
+}
+
+// Encode compresses float64 data using the specified mode.
+// For ModeFloat, uses ALP + Predictive Delta encoding (lossless).
+func Encode(input []float64, opts Options) ([]byte, error) {
+	if opts.Mode != ModeFloat {
+		return nil, fmt.Errorf("mode %v not supported for float64, use ModeFloat", opts.Mode)
+	}
+
+	// RiceParam <= 0 and ALPExponent <= 0 both mean auto-detect, matching the
+	// FloatEncoder defaults
+	return NewFloatEncoder(input).
+		WithRiceParam(opts.RiceParam).
+		WithPrecision(opts.ALPExponent).
+		Encode()
+}
+
+// Decode decompresses data produced by Encode (float64).
+func Decode(encoded []byte) ([]float64, error) {
+	return NewDecoder(encoded).DecodeFloat()
 }
 
 // AutoRiceParam calculates the optimal Rice parameter for given deltas.
@@ -1,7 +1,14 @@
 package alpine
 
 import (
+	"encoding/hex"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
 	"testing"
+
+	"github.com/ach968/alpine/internal"
 )
 
 func TestEncode_AutoRiceParam(t *testing.T) {
@@ -61,16 +68,65 @@ func TestModeConstants(t *testing.T) {
 	}
 }
 
-func TestEncode_InputTooShort(t *testing.T) {
-	// Float encode with < 2 elements
-	_, err := Encode([]float64{1.0}, Options{Mode: ModeFloat})
-	if err == nil {
-		t.Error("expected error for short float input, got nil")
+func TestEncode_ShortInput(t *testing.T) {
+	// Series with fewer than 2 elements encode and decode like any other
+	for _, input := range [][]float64{{}, {1.0}, {math.NaN()}, {math.Copysign(0, -1)}} {
+		encoded, err := Encode(input, Options{Mode: ModeFloat})
+		if err != nil {
+			t.Fatalf("%v: encode error: %v", input, err)
+		}
+
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Fatalf("%v: decode error: %v", input, err)
+		}
+
+		if len(decoded) != len(input) {
+			t.Fatalf("%v: expected %d values, got %d", input, len(input), len(decoded))
+		}
+		for i := range input {
+			if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+				t.Errorf("%v: round-trip[%d]: got %v", input, i, decoded[i])
+			}
+		}
 	}
+}
 
-	_, err = Encode([]float64{}, Options{Mode: ModeFloat})
-	if err == nil {
-		t.Error("expected error for empty float input, got nil")
+func TestIntEncoder_ShortInput(t *testing.T) {
+	for _, input := range [][]int64{{}, {-7}, {math.MaxInt64}} {
+		encoded, err := NewIntEncoder(input).Encode()
+		if err != nil {
+			t.Fatalf("%v: encode error: %v", input, err)
+		}
+
+		decoded, err := NewDecoder(encoded).DecodeInt()
+		if err != nil {
+			t.Fatalf("%v: decode error: %v", input, err)
+		}
+
+		if len(decoded) != len(input) {
+			t.Fatalf("%v: expected %d values, got %d", input, len(input), len(decoded))
+		}
+		for i := range input {
+			if decoded[i] != input[i] {
+				t.Errorf("%v: round-trip[%d]: expected %d, got %d", input, i, input[i], decoded[i])
+			}
+		}
+	}
+}
+
+func TestDecode_EmptySeriesKeepsKind(t *testing.T) {
+	encoded, err := NewIntEncoder(nil).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if _, err := NewDecoder(encoded).DecodeFloat(); err == nil {
+		t.Error("expected error decoding an empty int series as float, got nil")
+	}
+
+	if _, err := NewDecoder(encoded).At(0); err == nil {
+		t.Error("expected error for At on an empty series, got nil")
 	}
 }
 
@@ -113,3 +169,752 @@ func TestAutoRiceParam(t *testing.T) {
 		t.Errorf("expected positive rice param, got %d", param)
 	}
 }
+
+func TestFloatEncoder_Exceptions(t *testing.T) {
+	// Two-decimal readings with an occasional high-precision calibrated value
+	input := make([]float64, 1000)
+	for i := range input {
+		input[i] = float64(2000+i%37) / 100
+	}
+	input[123] = 20.3987654321012
+	input[777] = 1.0 / 3.0
+
+	encoded, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+
+	// The outliers must not force a wide exponent onto the whole series
+	if len(encoded) > len(input) {
+		t.Errorf("expected compact encoding, got %d bytes for %d values", len(encoded), len(input))
+	}
+}
+
+func TestFloatEncoder_LosslessFallback(t *testing.T) {
+	// No exponent round-trips these, so the encoder must switch to raw bits
+	input := make([]float64, 200)
+	for i := range input {
+		input[i] = math.Sin(float64(i)*0.01) / 3
+	}
+	input[3] = 1e-20
+	input[4] = 1e300
+	input[5] = 0.1 + 0.2
+	input[6] = 5e-324
+
+	encoded, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	header, _, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	if header.Mode != internal.ModeFloatXOR && header.Mode != internal.ModeFloatRD {
+		t.Errorf("expected a raw-bit mode, got %v", header.Mode)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+			t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestFloatEncoder_DecimalDataStaysALP(t *testing.T) {
+	input := []float64{10.5, 11.2, 12.8, 13.1, 14.5}
+
+	encoded, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if mode := blobMode(t, encoded); mode != internal.ModeFloat {
+		t.Errorf("expected ModeFloat, got %v", mode)
+	}
+}
+
+func TestDecodeInt_RejectsFloatXOR(t *testing.T) {
+	encoded, err := NewFloatEncoder([]float64{1e-20, 1e300, 1.0 / 3}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if _, err := NewDecoder(encoded).DecodeInt(); err == nil {
+		t.Error("expected error decoding float data as int, got nil")
+	}
+}
+
+func TestFloatEncoder_WithALPRD(t *testing.T) {
+	input := []float64{10.5, 11.2, 1.0 / 3, 13.1, math.Pi}
+
+	encoded, err := NewFloatEncoder(input).WithALPRD().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if mode := blobMode(t, encoded); mode != internal.ModeFloatRD {
+		t.Errorf("expected ModeFloatRD, got %v", mode)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+			t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestFloatEncoder_AutoALPRD(t *testing.T) {
+	// Trig output has no decimal structure but shares its high bits
+	input := make([]float64, 1000)
+	for i := range input {
+		input[i] = math.Sin(float64(i)*0.01) + 2
+	}
+
+	encoded, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if mode := blobMode(t, encoded); mode != internal.ModeFloatRD {
+		t.Errorf("expected ModeFloatRD, got %v", mode)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+			t.Fatalf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestFloatEncoder_HighPrecisionSize(t *testing.T) {
+	// Six decimals in [0, 1): residuals run to millions, far past a Rice
+	// parameter of 64, and must not all be escaped
+	rng := rand.New(rand.NewSource(1))
+	input := make([]float64, 10000)
+	for i := range input {
+		input[i] = math.Round(rng.Float64()*1e6) / 1e6
+	}
+
+	auto, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	wide, err := NewFloatEncoder(input).WithRiceParam(1 << 20).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if len(auto) > len(wide)*21/20 {
+		t.Errorf("expected about %d bytes with the automatic Rice parameter, got %d", len(wide), len(auto))
+	}
+	if bits := 8 * float64(len(auto)) / float64(len(input)); bits > 24 {
+		t.Errorf("expected at most 24 bits per value, got %.1f", bits)
+	}
+}
+
+func TestFloatEncoder_LargeRoundNumbers(t *testing.T) {
+	input := make([]float64, 500)
+	for i := range input {
+		input[i] = float64(300+i%50) * 1e10
+	}
+
+	encoded, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	header, _, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	if header.ALPFactor == 0 {
+		t.Errorf("expected a non-zero ALP factor, got exponent %d factor %d", header.ALPExp, header.ALPFactor)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestIntEncoder_SpikeResidual(t *testing.T) {
+	// A single 2^40 spike must not produce a unary run of 2^38 bits
+	input := []int64{10, 12, 14, 16, 1 << 40, 20, 22, 24}
+
+	encoded, err := NewIntEncoder(input).WithRiceParam(4).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if len(encoded) > internal.HeaderSize+64 {
+		t.Errorf("expected spike to be escaped, got %d bytes", len(encoded))
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %d, got %d", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestIntEncoder_NonPowerOf2RiceParam(t *testing.T) {
+	input := []int64{100, 112, 119, 133, 140, 158, 161, 170, 189, 195}
+
+	encoded, err := NewIntEncoder(input).WithRiceParam(12).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	header, _, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+	if header.RiceParam != 12 {
+		t.Errorf("expected rice param 12, got %d", header.RiceParam)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %d, got %d", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestFloatEncoder_Blocks(t *testing.T) {
+	// Two-decimal readings that turn into four-decimal, noisier readings
+	input := make([]float64, 3000)
+	for i := range input {
+		if i < 1500 {
+			input[i] = float64(2000+i%7) / 100
+		} else {
+			input[i] = float64(200000+(i*7919)%5000) / 10000
+		}
+	}
+
+	encoded, err := NewFloatEncoder(input).WithBlockSize(1000).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	header, size, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+	if header.Mode != internal.ModeBlocked || header.ValueCount != len(input) {
+		t.Fatalf("expected ModeBlocked with %d values, got %v with %d", len(input), header.Mode, header.ValueCount)
+	}
+
+	blocks, err := internal.UnmarshalBlocks(encoded[size:], header.ValueCount)
+	if err != nil {
+		t.Fatalf("blocks error: %v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+	if blocks[0].Header.ALPExp != 2 || blocks[2].Header.ALPExp != 4 {
+		t.Errorf("expected per-block exponents 2 and 4, got %d and %d", blocks[0].Header.ALPExp, blocks[2].Header.ALPExp)
+	}
+	if blocks[0].Header.RiceParam == blocks[2].Header.RiceParam {
+		t.Errorf("expected per-block rice parameters to differ, both are %d", blocks[0].Header.RiceParam)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if len(decoded) != len(input) {
+		t.Fatalf("length mismatch: expected %d, got %d", len(input), len(decoded))
+	}
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Fatalf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestIntEncoder_Blocks(t *testing.T) {
+	// The one-value tail joins the last block instead of forming its own
+	input := make([]int64, 2049)
+	for i := range input {
+		input[i] = int64(i*i) % 1000
+	}
+
+	encoded, err := NewIntEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	_, size, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+
+	blocks, err := internal.UnmarshalBlocks(encoded[size:], len(input))
+	if err != nil {
+		t.Fatalf("blocks error: %v", err)
+	}
+	if len(blocks) != 2 || blocks[1].Header.ValueCount != DefaultBlockSize+1 {
+		t.Errorf("expected blocks of %d and %d values, got %d blocks", DefaultBlockSize, DefaultBlockSize+1, len(blocks))
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Fatalf("round-trip[%d]: expected %d, got %d", i, input[i], decoded[i])
+		}
+	}
+
+	if _, err := NewDecoder(encoded).DecodeFloat(); err == nil {
+		t.Error("expected error decoding int blocks as float, got nil")
+	}
+}
+
+func TestIntEncoder_FrameOfReference(t *testing.T) {
+	// HTTP status codes: bounded, no trend, so residuals are twice as wide
+	codes := []int64{200, 404, 301, 500}
+	input := make([]int64, 500)
+	for i := range input {
+		input[i] = codes[(i*i+i/7)%len(codes)]
+	}
+
+	tests := []struct {
+		name string
+		enc  *IntEncoder
+		mode internal.Mode
+	}{
+		{"auto", NewIntEncoder(input), internal.ModeIntFOR},
+		{"forced", NewIntEncoder([]int64{10, 20, 30, 40}).WithFOR(), internal.ModeIntFOR},
+		{"explicit rice param", NewIntEncoder(input).WithRiceParam(64), internal.ModeInt},
+		{"trend", NewIntEncoder([]int64{1700000000, 1700000060, 1700000120, 1700000180, 1700000240}), internal.ModeInt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.enc.Encode()
+			if err != nil {
+				t.Fatalf("encode error: %v", err)
+			}
+
+			header, _, err := internal.Unmarshal(encoded)
+			if err != nil {
+				t.Fatalf("header error: %v", err)
+			}
+			if header.Mode != tt.mode {
+				t.Errorf("expected %v, got %v", tt.mode, header.Mode)
+			}
+
+			decoded, err := NewDecoder(encoded).DecodeInt()
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if !slices.Equal(decoded, tt.enc.data) {
+				t.Error("decoded values differ from input")
+			}
+		})
+	}
+
+	deltas, _, _, _ := internal.DeltaEncode(input)
+	rice, _ := NewIntEncoder(input).WithRiceParam(internal.AutoRiceParam(deltas)).Encode()
+	packed, _ := NewIntEncoder(input).Encode()
+	if len(packed) >= len(rice) {
+		t.Errorf("expected bit-packing to beat Rice: %d vs %d bytes", len(packed), len(rice))
+	}
+	// 9 bits cover the range 200-500
+	if want := (9*len(input) + 7) / 8; len(packed) > want+16 {
+		t.Errorf("expected about %d bytes, got %d", want, len(packed))
+	}
+}
+
+func TestEncoder_PFOR(t *testing.T) {
+	// Latencies: a steady baseline with occasional slow requests
+	rng := rand.New(rand.NewSource(3))
+	latencies := make([]float64, 2000)
+	micros := make([]int64, len(latencies))
+	for i := range latencies {
+		micros[i] = 12000 + rng.Int63n(400)
+		if rng.Intn(25) == 0 {
+			micros[i] += 200000 + rng.Int63n(2000000)
+		}
+		latencies[i] = float64(micros[i]) / 1000
+	}
+
+	floats, err := NewFloatEncoder(latencies).WithPFOR().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	ints, err := NewIntEncoder(micros).WithPFOR().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	riceFloats, _ := NewFloatEncoder(latencies).Encode()
+	riceInts, _ := NewIntEncoder(micros).Encode()
+
+	for _, tt := range []struct {
+		name    string
+		encoded []byte
+		mode    Mode
+		rice    []byte
+	}{
+		{"float", floats, ModeFloatPFOR, riceFloats},
+		{"int", ints, ModeIntPFOR, riceInts},
+	} {
+		info, err := Inspect(tt.encoded)
+		if err != nil {
+			t.Fatalf("%s: inspect error: %v", tt.name, err)
+		}
+		for i, b := range info.Blocks {
+			if b.Mode != tt.mode {
+				t.Errorf("%s: block %d: expected %v, got %v", tt.name, i, tt.mode, b.Mode)
+			}
+		}
+		if len(tt.encoded) >= len(tt.rice) {
+			t.Errorf("%s: expected patched bit-packing to beat Rice: %d vs %d bytes", tt.name, len(tt.encoded), len(tt.rice))
+		}
+	}
+
+	decodedFloats, err := NewDecoder(floats).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decodedFloats, latencies) {
+		t.Error("decoded floats differ from input")
+	}
+
+	decodedInts, err := NewDecoder(ints).DecodeIntRange(1000, 1500)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decodedInts, micros[1000:1500]) {
+		t.Error("decoded ints differ from input")
+	}
+}
+
+func TestEncoder_FastLanes(t *testing.T) {
+	floats := make([]float64, 5000)
+	for i := range floats {
+		floats[i] = 21.5 + float64((i*31)%97)*0.01
+	}
+	ints := make([]int64, 3000)
+	for i := range ints {
+		ints[i] = 1700000000 + int64(i)*60 + int64(i*7919)%7
+	}
+
+	encodedFloats, err := NewFloatEncoder(floats).WithFastLanes().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	encodedInts, err := NewIntEncoder(ints).WithFastLanes().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		encoded []byte
+		mode    Mode
+	}{
+		{"float", encodedFloats, ModeFloatFastLanes},
+		{"int", encodedInts, ModeIntFastLanes},
+	} {
+		info, err := Inspect(tt.encoded)
+		if err != nil {
+			t.Fatalf("%s: inspect error: %v", tt.name, err)
+		}
+		for i, b := range info.Blocks {
+			if b.Mode != tt.mode {
+				t.Errorf("%s: block %d: expected %v, got %v", tt.name, i, tt.mode, b.Mode)
+			}
+		}
+	}
+
+	decodedFloats, err := NewDecoder(encodedFloats).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decodedFloats, floats) {
+		t.Error("decoded floats differ from input")
+	}
+
+	decodedInts, err := NewDecoder(encodedInts).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decodedInts, ints) {
+		t.Error("decoded ints differ from input")
+	}
+
+	// The last option selecting a residual coding wins
+	encoded, _ := NewIntEncoder(ints).WithFastLanes().WithPFOR().Encode()
+	if info, _ := Inspect(encoded); info.Blocks[0].Mode != ModeIntPFOR {
+		t.Errorf("expected ModeIntPFOR, got %v", info.Blocks[0].Mode)
+	}
+}
+
+func TestEncoder_InvalidBlockSize(t *testing.T) {
+	if _, err := NewFloatEncoder([]float64{1, 2, 3}).WithBlockSize(1).Encode(); err == nil {
+		t.Error("expected error for float block size 1, got nil")
+	}
+
+	if _, err := NewIntEncoder([]int64{1, 2, 3}).WithBlockSize(0).Encode(); err == nil {
+		t.Error("expected error for int block size 0, got nil")
+	}
+
+	if _, err := NewIntEncoder([]int64{1, 2, 3}).WithBlockSize(internal.MaxSegmentValues).Encode(); err == nil {
+		t.Error("expected error for a block size at the segment limit, got nil")
+	}
+}
+
+// blobMode returns the mode recorded in the header of encoded
+func blobMode(t *testing.T, encoded []byte) internal.Mode {
+	t.Helper()
+	header, _, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+	return header.Mode
+}
+
+func TestDecode_RejectsForeignData(t *testing.T) {
+	encoded, err := NewIntEncoder([]int64{1, 2, 3}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	foreign := make([]byte, len(encoded))
+	copy(foreign, encoded)
+	copy(foreign, "PNG")
+	if _, err := NewDecoder(foreign).DecodeInt(); err == nil {
+		t.Error("expected error for data without magic signature, got nil")
+	}
+
+	future := make([]byte, len(encoded))
+	copy(future, encoded)
+	future[3] = internal.Version + 1
+	if _, err := NewDecoder(future).DecodeInt(); err == nil {
+		t.Error("expected error for unknown format version, got nil")
+	}
+}
+
+func TestDecode_CorruptValueCount(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("counts above the segment limit do not fit the platform's int")
+	}
+
+	decimals := make([]float64, 100)
+	ints := make([]int64, 100)
+	linear := make([]int64, 100)
+	for i := range decimals {
+		linear[i] = 1700000000 + int64(i)*60
+		decimals[i] = 21.35 + float64(i%7)*0.05
+		ints[i] = 1700000000 + int64(i)*60 + int64(i%3)
+	}
+	encode := func(e interface{ Encode() ([]byte, error) }) []byte {
+		encoded, _ := e.Encode()
+		return encoded
+	}
+
+	tests := []struct {
+		mode    internal.Mode
+		encoded []byte
+	}{
+		{internal.ModeFloat, encode(NewFloatEncoder(decimals))},
+		{internal.ModeInt, encode(NewIntEncoder(ints))},
+		{internal.ModeFloatXOR, encode(NewFloatEncoder([]float64{math.Pi, math.E, math.Sqrt2}))},
+		{internal.ModeFloatRD, encode(NewFloatEncoder(decimals).WithALPRD())},
+		{internal.ModeIntFOR, encode(NewIntEncoder([]int64{200, 404, 200, 500, 301, 200}).WithFOR())},
+		{internal.ModeIntPFOR, encode(NewIntEncoder(ints).WithPFOR())},
+		{internal.ModeFloatPFOR, encode(NewFloatEncoder(decimals).WithPFOR())},
+		{internal.ModeIntFastLanes, encode(NewIntEncoder(ints).WithFastLanes())},
+		{internal.ModeFloatFastLanes, encode(NewFloatEncoder(decimals).WithFastLanes())},
+		{internal.ModeBlocked, encode(NewIntEncoder(ints).WithBlockSize(10))},
+		// Zero-width payloads hold any count up to the segment limit
+		{internal.ModeIntFOR, encode(NewIntEncoder([]int64{7, 7, 7}).WithFOR())},
+		{internal.ModeIntPFOR, encode(NewIntEncoder(linear).WithPFOR())},
+		{internal.ModeIntFastLanes, encode(NewIntEncoder(linear).WithFastLanes())},
+	}
+
+	for _, tt := range tests {
+		if mode := blobMode(t, tt.encoded); mode != tt.mode {
+			t.Fatalf("expected %v, got %v", tt.mode, mode)
+		}
+		header, size, _ := internal.Unmarshal(tt.encoded)
+
+		for _, count := range []int{internal.MaxSegmentValues + 1, math.MaxInt32, 1 << 31, 1 << 62} {
+			header.ValueCount = count
+			corrupt := marshalBlob(header, tt.encoded[size:], false)
+
+			if err := Verify(corrupt); err == nil {
+				t.Errorf("%v with %d values: expected verify error, got nil", tt.mode, count)
+			}
+			if _, err := NewDecoder(corrupt).Decode(); err == nil {
+				t.Errorf("%v with %d values: expected decode error, got nil", tt.mode, count)
+			}
+		}
+	}
+}
+
+func TestDecode_EmptyBlockSection(t *testing.T) {
+	// Compact header, ModeBlocked, no values, and a block count of 0
+	encoded, _ := hex.DecodeString("414c5084004020000000")
+
+	if err := Verify(encoded); err == nil {
+		t.Error("expected verify error, got nil")
+	}
+	if _, err := NewDecoder(encoded).DecodeFloat(); err == nil {
+		t.Error("expected decode error, got nil")
+	}
+	if _, err := NewDecoder(encoded).Kind(); err == nil {
+		t.Error("expected kind error, got nil")
+	}
+	if _, err := Inspect(encoded); err == nil {
+		t.Error("expected inspect error, got nil")
+	}
+}
+
+func TestEncoder_WideRiceParam(t *testing.T) {
+	input := []int64{0, 1000, 1900, 3100, 3950, 5200, 6000}
+
+	encoded, err := NewIntEncoder(input).WithRiceParam(300).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	header, _, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+	if header.RiceParam != 300 {
+		t.Errorf("expected rice param 300, got %d", header.RiceParam)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %d, got %d", i, input[i], decoded[i])
+		}
+	}
+
+	// The block index stores the same parameter
+	blocked, err := NewIntEncoder(input).WithRiceParam(300).WithBlockSize(3).Encode()
+	if err != nil {
+		t.Fatalf("blocked encode error: %v", err)
+	}
+	if _, err := NewDecoder(blocked).DecodeInt(); err != nil {
+		t.Errorf("blocked decode error: %v", err)
+	}
+}
+
+func TestEncoder_RejectsUnrepresentableParams(t *testing.T) {
+	if _, err := NewFloatEncoder([]float64{1.5, 2.5}).WithPrecision(internal.MaxALPExponent + 1).Encode(); err == nil {
+		t.Error("expected error for precision above the maximum, got nil")
+	}
+
+	if strconv.IntSize < 64 {
+		t.Skip("rice parameter limit equals the platform's int range")
+	}
+	tooLarge := internal.MaxRiceParam
+	tooLarge++
+
+	if _, err := NewFloatEncoder([]float64{1.5, 2.5}).WithRiceParam(tooLarge).Encode(); err == nil {
+		t.Error("expected error for float rice parameter above the maximum, got nil")
+	}
+
+	if _, err := NewIntEncoder([]int64{1, 2}).WithRiceParam(tooLarge).Encode(); err == nil {
+		t.Error("expected error for int rice parameter above the maximum, got nil")
+	}
+}
+
+func TestEncode_CompactHeader(t *testing.T) {
+	// A per-minute rollup: the header must not dwarf the payload
+	input := make([]float64, 60)
+	for i := range input {
+		input[i] = 21.5 + float64(i%4)*0.25
+	}
+
+	encoded, err := NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	header, size, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("header error: %v", err)
+	}
+	if size >= internal.HeaderSize {
+		t.Errorf("expected a compact header, got %d bytes", size)
+	}
+
+	decoded, err := NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Fatalf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+
+	// The same payload behind a full header decodes identically
+	full := append(header.Marshal(), encoded[size:]...)
+	decoded, err = NewDecoder(full).DecodeFloat()
+	if err != nil {
+		t.Fatalf("full header decode error: %v", err)
+	}
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Fatalf("full header round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package alpine
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// Appender adds values to the end of an encoded series without decoding and
+// re-encoding it. The predictor only needs the last two values and the
+// Golomb-Rice stream can be extended bit by bit, so values are appended in
+// place to the last block, using its parameters.
+//
+// Values that don't fit those parameters (a residual would be escaped, or too
+// many floats don't survive the block's ALP exponent), or that follow a block
+// of another mode, fall back to a new block: the last block is re-encoded
+// together with them, picking new parameters. Once the last block is full,
+// appended values start a new block of their own. Either way the cost of an
+// append is bounded by the block size, not the series length.
+type Appender struct {
+	kind      Kind
+	checksum  bool
+	blockSize int
+
+	// Blocks before the last one, never touched again
+	headers  []*internal.Header
+	payloads [][]byte
+
+	tail appendTail
+}
+
+// appendTail is the state of the last block
+type appendTail struct {
+	header *internal.Header // ValueCount is kept current
+
+	// inPlace is set for ModeInt and ModeFloat blocks with both seed values,
+	// which are extended with the fields below; other blocks keep payload
+	inPlace    bool
+	packed     internal.PackedData  // Golomb-Rice stream of the residuals
+	exceptions []internal.Exception // ModeFloat only
+	prev1      int64                // last value, scaled for ModeFloat
+	prev2      int64                // value before the last one
+	payload    []byte
+}
+
+// NewAppender opens an encoded float64 or int64 series for appending. The
+// blob is copied, so the caller may reuse it. Series with nulls, time series
+// and frames cannot be appended to.
+func NewAppender(encoded []byte) (*Appender, error) {
+	d := NewDecoder(slices.Clone(encoded))
+
+	header, blocks, err := d.denseIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := d.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind != KindFloat && kind != KindInt {
+		return nil, fmt.Errorf("cannot append to a %v blob", kind)
+	}
+
+	a := &Appender{
+		kind:      kind,
+		checksum:  header.Flags&internal.FlagChecksum != 0,
+		blockSize: DefaultBlockSize,
+	}
+
+	last := len(blocks) - 1
+	for _, b := range blocks[:last] {
+		a.headers = append(a.headers, b.Header)
+		a.payloads = append(a.payloads, b.Payload)
+	}
+
+	if err := a.openTail(blocks[last].Header, blocks[last].Payload); err != nil {
+		return nil, blockError(header, last, err)
+	}
+
+	return a, nil
+}
+
+// WithBlockSize sets the number of values after which appending starts a new
+// block (default DefaultBlockSize)
+func (a *Appender) WithBlockSize(size int) *Appender {
+	a.blockSize = size
+	return a
+}
+
+// Len returns the number of values in the series
+func (a *Appender) Len() int {
+	n := a.tail.header.ValueCount
+	for _, h := range a.headers {
+		n += h.ValueCount
+	}
+	return n
+}
+
+// AppendFloat appends values to a float64 series
+func (a *Appender) AppendFloat(values ...float64) error {
+	if a.kind != KindFloat {
+		return fmt.Errorf("cannot append float64 values to a %v series", a.kind)
+	}
+
+	for i, v := range values {
+		if t := &a.tail; t.inPlace {
+			scaled, exact := internal.ALPScale(v, t.header.ALPExp, t.header.ALPFactor)
+			if a.appendScaled(scaled, exact, math.Float64bits(v)) {
+				continue
+			}
+		}
+		return appendBlocks(a, values[i:], decodeFloatSegment, encodeFloatBlock)
+	}
+	return nil
+}
+
+// AppendInt appends values to an int64 series
+func (a *Appender) AppendInt(values ...int64) error {
+	if a.kind != KindInt {
+		return fmt.Errorf("cannot append int64 values to a %v series", a.kind)
+	}
+
+	for i, v := range values {
+		if a.tail.inPlace && a.appendScaled(v, true, 0) {
+			continue
+		}
+		return appendBlocks(a, values[i:], decodeIntSegment, encodeIntBlock)
+	}
+	return nil
+}
+
+// Bytes returns the encoded series with every appended value. The blob keeps
+// the checksum setting of the one the Appender was opened with.
+func (a *Appender) Bytes() []byte {
+	headers := append(slices.Clip(a.headers), a.tail.header)
+	payloads := append(slices.Clip(a.payloads), a.tail.encoded())
+
+	// marshalBlob sets flags on the header it is given
+	header := *headers[0]
+	payload := payloads[0]
+	if len(headers) > 1 {
+		header = internal.Header{Mode: internal.ModeBlocked, ValueCount: a.Len()}
+		payload = internal.MarshalBlocks(headers, payloads)
+	}
+
+	return marshalBlob(&header, payload, a.checksum)
+}
+
+// appendScaled appends one value to the last block in place and reports
+// whether it fit. scaled is the value as the predictor sees it; a float that
+// is not exact is stored as an exception with its raw bits.
+func (a *Appender) appendScaled(scaled int64, exact bool, raw uint64) bool {
+	t := &a.tail
+	n := t.header.ValueCount
+	if n >= a.blockSize {
+		return false
+	}
+
+	// The encoder gives up on ALP beyond a quarter of exceptions
+	if !exact {
+		if len(t.exceptions)+1 > (n+1)/4 {
+			return false
+		}
+		// Exception slots repeat the previous value, as in ALPEncode
+		scaled = t.prev1
+	}
+
+	predicted := t.prev1 + (t.prev1 - t.prev2)
+	zigzagged, err := internal.ZigZagEncode([]int64{scaled - predicted})
+	if err != nil {
+		return false
+	}
+
+	// An escaped residual means the Rice parameter no longer fits the data
+	threshold := uint64(internal.DefaultEscapeThreshold)
+	if len(t.packed.Data) > 0 {
+		threshold = uint64(t.packed.Data[0])
+	}
+	if zigzagged[0]/uint64(t.header.RiceParam) >= threshold {
+		return false
+	}
+
+	packed, err := internal.GolombRiceAppend(t.packed, zigzagged, t.header.RiceParam)
+	if err != nil {
+		return false
+	}
+
+	t.packed = packed
+	if !exact {
+		t.exceptions = append(t.exceptions, internal.Exception{Position: n, Bits: raw})
+	}
+	t.prev2, t.prev1 = t.prev1, scaled
+	t.header.ValueCount++
+	return true
+}
+
+// appendBlocks appends values that did not fit the last block in place. They
+// start a new block when the last block is full; otherwise, or when there are
+// too few of them to seed a block, the last block is re-encoded with them.
+func appendBlocks[T any](a *Appender, values []T, decode func(*internal.Header, []byte) ([]T, error), encode func([]T) (*internal.Header, []byte, error)) error {
+	if err := checkBlockSize(a.blockSize); err != nil {
+		return err
+	}
+
+	t := &a.tail
+	if t.header.ValueCount >= a.blockSize && len(values) >= 2 {
+		a.headers = append(a.headers, t.header)
+		a.payloads = append(a.payloads, t.encoded())
+	} else {
+		existing, err := decode(t.header, t.encoded())
+		if err != nil {
+			return err
+		}
+		values = append(existing, values...)
+	}
+
+	headers, payloads, err := encodeBlocks(len(values), a.blockSize, func(start, end int) (*internal.Header, []byte, error) {
+		return encode(values[start:end])
+	})
+	if err != nil {
+		return err
+	}
+
+	last := len(headers) - 1
+	a.headers = append(a.headers, headers[:last]...)
+	a.payloads = append(a.payloads, payloads[:last]...)
+	return a.openTail(headers[last], payloads[last])
+}
+
+// openTail recovers the state needed to extend the block with header h and
+// payload
+func (a *Appender) openTail(h *internal.Header, payload []byte) error {
+	header := *h
+	header.Flags = 0
+	t := appendTail{header: &header, payload: payload}
+
+	if (h.Mode != internal.ModeInt && h.Mode != internal.ModeFloat) || h.ValueCount < 2 {
+		a.tail = t
+		return nil
+	}
+
+	t.inPlace = true
+	t.prev2, t.prev1 = h.First, h.Second
+
+	rest := payload
+	if deltaCount := h.ValueCount - 2; deltaCount > 0 {
+		zigzagged, packed, err := internal.GolombRiceDecodePacked(payload, deltaCount, h.RiceParam)
+		if err != nil {
+			return fmt.Errorf("golomb-rice decode: %w", err)
+		}
+		// The stream grows in place, so it must not share memory with rest
+		t.packed = internal.PackedData{
+			Data:       slices.Clone(packed.Data),
+			BitCount:   packed.BitCount,
+			ValueCount: packed.ValueCount,
+		}
+		rest = payload[len(packed.Data):]
+
+		deltas, err := internal.ZigZagDecode(zigzagged)
+		if err != nil {
+			return fmt.Errorf("zigzag decode: %w", err)
+		}
+		scaled, err := internal.DeltaDecode(deltas, h.First, h.Second)
+		if err != nil {
+			return fmt.Errorf("delta decode: %w", err)
+		}
+		t.prev2, t.prev1 = scaled[len(scaled)-2], scaled[len(scaled)-1]
+	}
+
+	if h.Mode == internal.ModeFloat {
+		exceptions, _, err := internal.UnmarshalExceptions(rest, h.ValueCount)
+		if err != nil {
+			return fmt.Errorf("exceptions: %w", err)
+		}
+		t.exceptions = exceptions
+	}
+
+	a.tail = t
+	return nil
+}
+
+// encoded returns the payload of the last block
+func (t *appendTail) encoded() []byte {
+	if !t.inPlace {
+		return t.payload
+	}
+	if t.header.Mode != internal.ModeFloat {
+		return t.packed.Data
+	}
+	return append(slices.Clip(t.packed.Data), internal.MarshalExceptions(t.exceptions)...)
+}
@@ -0,0 +1,233 @@
+package alpine
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/ach968/alpine/internal"
+)
+
+func TestAppender_IntInPlace(t *testing.T) {
+	timestamps := []int64{1700000000, 1700000060, 1700000120, 1700000180, 1700000240}
+
+	encoded, err := NewIntEncoder(timestamps).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	a, err := NewAppender(encoded)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	more := []int64{1700000300, 1700000361, 1700000420}
+	if err := a.AppendInt(more...); err != nil {
+		t.Fatalf("append error: %v", err)
+	}
+	appended := a.Bytes()
+
+	// The existing stream is kept, only its last partial byte is extended
+	oldHeader, oldSize, _ := internal.Unmarshal(encoded)
+	newHeader, newSize, err := internal.Unmarshal(appended)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if newHeader.Mode != oldHeader.Mode || newHeader.RiceParam != oldHeader.RiceParam || newHeader.First != oldHeader.First {
+		t.Errorf("expected the header parameters to be kept, got %+v from %+v", newHeader, oldHeader)
+	}
+	oldPayload, newPayload := encoded[oldSize:], appended[newSize:]
+	if !slices.Equal(oldPayload[:len(oldPayload)-1], newPayload[:len(oldPayload)-1]) {
+		t.Error("expected the existing payload to be a prefix of the appended one")
+	}
+
+	decoded, err := NewDecoder(appended).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if want := append(timestamps, more...); !slices.Equal(decoded, want) {
+		t.Errorf("expected %v, got %v", want, decoded)
+	}
+}
+
+func TestAppender_Float(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []float64
+		append [][]float64
+	}{
+		{"in place", []float64{21.35, 21.40, 21.38}, [][]float64{{21.41}, {21.45, 21.43}}},
+		{"exceptions", []float64{21.35, 21.40, 21.38, 21.41, 21.45, 21.43, 21.44, 21.46}, [][]float64{{math.NaN()}, {21.5}}},
+		{"precision change", []float64{21.35, 21.40, 21.38}, [][]float64{{math.Pi, math.E}, {1.5}}},
+		{"outlier", []float64{21.35, 21.40, 21.38}, [][]float64{{1e12}, {21.4}}},
+		{"from empty", nil, [][]float64{{1.5}, {2.5}, {3.5, 4.5}}},
+		{"from xor", []float64{math.Pi, math.E, math.Sqrt2}, [][]float64{{math.Phi}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := NewFloatEncoder(tt.data).Encode()
+			if err != nil {
+				t.Fatalf("encode error: %v", err)
+			}
+
+			want := slices.Clone(tt.data)
+			for _, values := range tt.append {
+				a, err := NewAppender(encoded)
+				if err != nil {
+					t.Fatalf("open error: %v", err)
+				}
+				if err := a.AppendFloat(values...); err != nil {
+					t.Fatalf("append error: %v", err)
+				}
+				encoded = a.Bytes()
+				want = append(want, values...)
+			}
+
+			decoded, err := NewDecoder(encoded).DecodeFloat()
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if len(decoded) != len(want) {
+				t.Fatalf("expected %d values, got %d", len(want), len(decoded))
+			}
+			for i := range want {
+				if math.Float64bits(decoded[i]) != math.Float64bits(want[i]) {
+					t.Errorf("index %d: expected %v, got %v", i, want[i], decoded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAppender_StartsNewBlocks(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+
+	var want []int64
+	encoded, err := NewIntEncoder(nil).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	a, err := NewAppender(encoded)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	a.WithBlockSize(64)
+
+	next := int64(1700000000)
+	for range 100 {
+		batch := make([]int64, 1+rng.Intn(4))
+		for i := range batch {
+			next += 60 + rng.Int63n(3) - 1
+			if rng.Intn(50) == 0 {
+				next += 1 << 40
+			}
+			batch[i] = next
+		}
+		if err := a.AppendInt(batch...); err != nil {
+			t.Fatalf("append error: %v", err)
+		}
+		want = append(want, batch...)
+	}
+
+	if a.Len() != len(want) {
+		t.Errorf("expected length %d, got %d", len(want), a.Len())
+	}
+
+	encoded = a.Bytes()
+	decoded, err := NewDecoder(encoded).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decoded, want) {
+		t.Fatal("decoded values differ from appended ones")
+	}
+
+	info, err := Inspect(encoded)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	if len(info.Blocks) < len(want)/64 {
+		t.Errorf("expected at least %d blocks, got %d", len(want)/64, len(info.Blocks))
+	}
+	for _, b := range info.Blocks[:len(info.Blocks)-1] {
+		if b.ValueCount < 64 {
+			t.Errorf("block at %d: expected a full block, got %d values", b.Start, b.ValueCount)
+		}
+	}
+
+	// Appending costs about as much as encoding in one go
+	oneShot, _ := NewIntEncoder(want).WithBlockSize(64).Encode()
+	if len(encoded) > len(oneShot)*5/4 {
+		t.Errorf("appended blob of %d bytes, one-shot encoding %d", len(encoded), len(oneShot))
+	}
+}
+
+func TestAppender_KeepsChecksum(t *testing.T) {
+	encoded, err := NewFloatEncoder([]float64{1.5, 2.5, 3.5}).WithChecksum().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	a, err := NewAppender(encoded)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	if err := a.AppendFloat(4.5); err != nil {
+		t.Fatalf("append error: %v", err)
+	}
+
+	info, err := Inspect(a.Bytes())
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	if !info.Checksum || info.ValueCount != 4 {
+		t.Errorf("expected 4 values with a checksum, got %+v", info)
+	}
+}
+
+func TestAppender_Invalid(t *testing.T) {
+	floats, _ := NewFloatEncoder([]float64{1.5, 2.5}).Encode()
+	nullable, _ := NewIntEncoder([]int64{1, 0}).WithValidity([]bool{true, false}).Encode()
+	timeSeries, _ := NewTimeSeriesEncoder([]int64{1, 2}, []float64{1.5, 2.5}).Encode()
+
+	for name, blob := range map[string][]byte{"nullable": nullable, "time series": timeSeries, "garbage": []byte("garbage")} {
+		if _, err := NewAppender(blob); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+
+	a, err := NewAppender(floats)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	if err := a.AppendInt(3); err == nil {
+		t.Error("expected error appending ints to a float series, got nil")
+	}
+}
+
+func TestAppender_FrameOfReferenceTail(t *testing.T) {
+	status := []int64{200, 404, 200, 500, 301, 200, 404, 503}
+	encoded, err := NewIntEncoder(status).WithFOR().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Bit-packed blocks are re-encoded with the new values
+	a, err := NewAppender(encoded)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	if err := a.AppendInt(200, 429); err != nil {
+		t.Fatalf("append error: %v", err)
+	}
+
+	decoded, err := NewDecoder(a.Bytes()).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if want := append(status, 200, 429); !slices.Equal(decoded, want) {
+		t.Errorf("expected %v, got %v", want, decoded)
+	}
+}
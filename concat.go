@@ -0,0 +1,256 @@
+package alpine
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// Concat joins two encoded series of the same kind into one blob that decodes
+// to the values of a followed by those of b. Every block carries its own seed
+// values, so the blocks of both series are copied as they are; only the last
+// block of a and the first block of b are decoded and re-encoded together when
+// they fit in one block, which re-seeds the predictor across the boundary and
+// keeps repeated concatenation of short chunks from piling up tiny blocks. The
+// re-encoded block keeps the residual coding (WithPFOR, WithFastLanes) and the
+// explicit precision that both blocks share; where they differ, parameters are
+// picked automatically.
+//
+// Time series are joined column by column, and so are frames, which must have
+// the same column names and kinds in the same order. Series with nulls keep
+// their validity. The result carries a checksum if either input does.
+func Concat(a, b []byte) ([]byte, error) {
+	da, db := NewDecoder(a), NewDecoder(b)
+
+	ha, _, err := da.blockIndex()
+	if err != nil {
+		return nil, fmt.Errorf("first series: %w", err)
+	}
+	hb, _, err := db.blockIndex()
+	if err != nil {
+		return nil, fmt.Errorf("second series: %w", err)
+	}
+
+	kind, err := concatKind(da, db)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := (ha.Flags|hb.Flags)&internal.FlagChecksum != 0
+
+	if kind == KindFloat || kind == KindInt {
+		header, payload, err := concatSeries(kind, da, db)
+		if err != nil {
+			return nil, err
+		}
+
+		var validity []byte
+		if da.valid != nil || db.valid != nil {
+			validity = internal.MarshalValidity(append(validityOf(da), validityOf(db)...))
+		}
+		return marshalBlob(header, withValidity(header, validity, payload), checksum), nil
+	}
+
+	ca, namesA, err := da.columnIndex()
+	if err != nil {
+		return nil, fmt.Errorf("first series: %w", err)
+	}
+	cb, namesB, err := db.columnIndex()
+	if err != nil {
+		return nil, fmt.Errorf("second series: %w", err)
+	}
+	if !slices.Equal(namesA, namesB) || len(ca) != len(cb) {
+		return nil, fmt.Errorf("cannot concatenate frames with columns %q and %q", namesA, namesB)
+	}
+
+	headers := make([]*internal.Header, len(ca))
+	payloads := make([][]byte, len(ca))
+	for i := range ca {
+		kind, err := concatKind(ca[i], cb[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+		headers[i], payloads[i], err = concatSeries(kind, ca[i], cb[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+	}
+
+	header := &internal.Header{
+		Mode:       ha.Mode,
+		ValueCount: ha.ValueCount + hb.ValueCount,
+	}
+
+	payload := internal.MarshalColumns(headers, payloads)
+	if kind == KindFrame {
+		payload = internal.MarshalFrame(namesA, headers, payloads)
+	}
+
+	return marshalBlob(header, payload, checksum), nil
+}
+
+// concatKind returns the kind shared by the series of da and db
+func concatKind(da, db *Decoder) (Kind, error) {
+	ka, err := da.Kind()
+	if err != nil {
+		return 0, err
+	}
+	kb, err := db.Kind()
+	if err != nil {
+		return 0, err
+	}
+	if ka != kb {
+		return 0, fmt.Errorf("cannot concatenate a %v series with a %v series", ka, kb)
+	}
+	return ka, nil
+}
+
+// concatSeries joins the blocks of two float64 or int64 series, whose block
+// indexes are already parsed, and returns the header and payload of the
+// result, without flags
+func concatSeries(kind Kind, da, db *Decoder) (*internal.Header, []byte, error) {
+	left := nonEmptyBlocks(da.blocks)
+	right := nonEmptyBlocks(db.blocks)
+
+	if len(left) > 0 && len(right) > 0 {
+		l, r := left[len(left)-1], right[0]
+		var merged []internal.Block
+		var err error
+		switch kind {
+		case KindFloat:
+			merged, err = mergeBlocks(l, r, decodeFloatSegment, joinFloatEncoder(l.Header, r.Header))
+		default:
+			merged, err = mergeBlocks(l, r, decodeIntSegment, joinIntEncoder(l.Header, r.Header))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		left = append(left[:len(left)-1:len(left)-1], merged...)
+		right = right[1:]
+	}
+
+	blocks := append(left, right...)
+	switch len(blocks) {
+	case 0:
+		// Both series are empty: any of the empty segments will do
+		header := *da.blocks[0].Header
+		header.Flags = 0
+		return &header, da.blocks[0].Payload, nil
+	case 1:
+		header := *blocks[0].Header
+		header.Flags = 0
+		return &header, blocks[0].Payload, nil
+	}
+
+	header := &internal.Header{Mode: internal.ModeBlocked}
+	headers := make([]*internal.Header, len(blocks))
+	payloads := make([][]byte, len(blocks))
+	for i, b := range blocks {
+		headers[i], payloads[i] = b.Header, b.Payload
+		header.ValueCount += b.Header.ValueCount
+	}
+
+	return header, internal.MarshalBlocks(headers, payloads), nil
+}
+
+// mergeBlocks returns the blocks that replace the adjacent blocks left and
+// right. They are kept as they are when both hold at least two values and
+// together would overflow a block; otherwise they are decoded and re-encoded
+// as one series.
+func mergeBlocks[T any](left, right internal.Block, decode func(*internal.Header, []byte) ([]T, error), encode func([]T) (*internal.Header, []byte, error)) ([]internal.Block, error) {
+	n := left.Header.ValueCount + right.Header.ValueCount
+	if left.Header.ValueCount >= 2 && right.Header.ValueCount >= 2 && n > DefaultBlockSize {
+		return []internal.Block{left, right}, nil
+	}
+
+	values, err := decode(left.Header, left.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("first series: %w", err)
+	}
+	more, err := decode(right.Header, right.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("second series: %w", err)
+	}
+	values = append(values, more...)
+
+	headers, payloads, err := encodeBlocks(n, DefaultBlockSize, func(start, end int) (*internal.Header, []byte, error) {
+		return encode(values[start:end])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]internal.Block, len(headers))
+	for i := range headers {
+		blocks[i] = internal.Block{Header: headers[i], Payload: payloads[i]}
+	}
+	return blocks, nil
+}
+
+// nonEmptyBlocks drops the single empty segment of an empty series
+func nonEmptyBlocks(blocks []internal.Block) []internal.Block {
+	if len(blocks) == 1 && blocks[0].Header.ValueCount == 0 {
+		return nil
+	}
+	return blocks
+}
+
+// joinFloatEncoder returns the encoder of the blocks replacing the adjacent
+// blocks with headers a and b. It keeps the residual coding and the explicit
+// ALP exponent they share, and otherwise picks parameters automatically.
+func joinFloatEncoder(a, b *internal.Header) func([]float64) (*internal.Header, []byte, error) {
+	e := NewFloatEncoder(nil)
+	e.residuals = joinCoding(a, b)
+
+	// An explicit precision is stored with a factor of 0
+	exponent := -1
+	if a.Mode.UsesALP() && b.Mode.UsesALP() && a.ALPExp == b.ALPExp && a.ALPExp > 0 && a.ALPFactor == 0 && b.ALPFactor == 0 {
+		exponent = a.ALPExp
+	}
+
+	return func(data []float64) (*internal.Header, []byte, error) {
+		return e.encodeSegment(data, exponent, 0)
+	}
+}
+
+// joinIntEncoder is joinFloatEncoder for int64 blocks
+func joinIntEncoder(a, b *internal.Header) func([]int64) (*internal.Header, []byte, error) {
+	coding := joinCoding(a, b)
+	return func(data []int64) (*internal.Header, []byte, error) {
+		return encodeInt(data, 0, coding)
+	}
+}
+
+// joinCoding returns the residual coding of the blocks with headers a and b,
+// or Golomb-Rice when they differ
+func joinCoding(a, b *internal.Header) residualCoding {
+	if ca := codingOf(a.Mode); ca == codingOf(b.Mode) {
+		return ca
+	}
+	return residualRice
+}
+
+// codingOf returns the residual coding of mode
+func codingOf(mode internal.Mode) residualCoding {
+	switch {
+	case mode.UsesPFOR():
+		return residualPFOR
+	case mode.UsesFastLanes():
+		return residualFastLanes
+	default:
+		return residualRice
+	}
+}
+
+// encodeFloatBlock encodes one block of float64 values with automatically
+// chosen parameters and Golomb-Rice residuals
+func encodeFloatBlock(data []float64) (*internal.Header, []byte, error) {
+	return NewFloatEncoder(nil).encodeSegment(data, -1, 0)
+}
+
+// encodeIntBlock encodes one block of int64 values with an automatically
+// chosen Rice parameter
+func encodeIntBlock(data []int64) (*internal.Header, []byte, error) {
+	return encodeInt(data, 0, residualRice)
+}
@@ -0,0 +1,292 @@
+package alpine
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestConcat_Float(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+	}{
+		{"short chunks", []float64{21.35, 21.40, 21.38}, []float64{21.41, 21.45}},
+		{"empty first", nil, []float64{1.5, 2.5}},
+		{"empty second", []float64{1.5, 2.5}, nil},
+		{"both empty", nil, nil},
+		{"single values", []float64{1.5}, []float64{2.5}},
+		{"different precision", []float64{1.5, 2.5, 3.5}, []float64{math.Pi, math.E, math.NaN()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := NewFloatEncoder(tt.a).Encode()
+			b, _ := NewFloatEncoder(tt.b).Encode()
+
+			joined, err := Concat(a, b)
+			if err != nil {
+				t.Fatalf("concat error: %v", err)
+			}
+
+			decoded, err := NewDecoder(joined).DecodeFloat()
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			want := append(slices.Clone(tt.a), tt.b...)
+			if len(decoded) != len(want) {
+				t.Fatalf("expected %d values, got %d", len(want), len(decoded))
+			}
+			for i := range want {
+				if math.Float64bits(decoded[i]) != math.Float64bits(want[i]) {
+					t.Errorf("index %d: expected %v, got %v", i, want[i], decoded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConcat_CopiesBlocks(t *testing.T) {
+	n := 3000
+	first := make([]int64, n)
+	second := make([]int64, n)
+	for i := range n {
+		first[i] = 1700000000 + int64(i)*60
+		second[i] = first[n-1] + int64(i+1)*15
+	}
+
+	a, _ := NewIntEncoder(first).Encode()
+	b, _ := NewIntEncoder(second).Encode()
+
+	joined, err := Concat(a, b)
+	if err != nil {
+		t.Fatalf("concat error: %v", err)
+	}
+
+	decoded, err := NewDecoder(joined).DecodeInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decoded, append(slices.Clone(first), second...)) {
+		t.Fatal("decoded values differ from the concatenation")
+	}
+
+	// Full blocks are byte copies of the inputs
+	infoA, _ := Inspect(a)
+	infoB, _ := Inspect(b)
+	info, err := Inspect(joined)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	if len(info.Blocks) != len(infoA.Blocks)+len(infoB.Blocks) {
+		t.Errorf("expected %d blocks, got %d", len(infoA.Blocks)+len(infoB.Blocks), len(info.Blocks))
+	}
+	da, dj := NewDecoder(a), NewDecoder(joined)
+	_, blocksA, _ := da.blockIndex()
+	_, blocks, _ := dj.blockIndex()
+	for i, block := range blocksA {
+		if !bytes.Equal(block.Payload, blocks[i].Payload) {
+			t.Errorf("block %d: expected the payload to be copied", i)
+		}
+	}
+}
+
+func TestConcat_MergesShortChunks(t *testing.T) {
+	var want []float64
+	joined, _ := NewFloatEncoder(nil).Encode()
+
+	// An hour of per-minute rollups at a time, for a day
+	for hour := range 24 {
+		chunk := make([]float64, 60)
+		for i := range chunk {
+			chunk[i] = 20 + float64((hour*60+i)%37)*0.05
+		}
+		b, _ := NewFloatEncoder(chunk).Encode()
+
+		var err error
+		joined, err = Concat(joined, b)
+		if err != nil {
+			t.Fatalf("hour %d: concat error: %v", hour, err)
+		}
+		want = append(want, chunk...)
+	}
+
+	decoded, err := NewDecoder(joined).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(decoded, want) {
+		t.Fatal("decoded values differ from the concatenation")
+	}
+
+	info, err := Inspect(joined)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	if len(info.Blocks) != 2 {
+		t.Errorf("expected 2 blocks for %d values, got %d", len(want), len(info.Blocks))
+	}
+}
+
+func TestConcat_KeepsCoding(t *testing.T) {
+	chunk := func(offset int) []float64 {
+		values := make([]float64, 40)
+		for i := range values {
+			values[i] = 20 + float64((offset+i)%37)*0.05
+		}
+		return values
+	}
+
+	tests := []struct {
+		name     string
+		encoder  func([]float64) *FloatEncoder
+		mode     Mode
+		exponent int
+	}{
+		{"pfor", func(v []float64) *FloatEncoder { return NewFloatEncoder(v).WithPFOR() }, ModeFloatPFOR, 2},
+		{"fastlanes", func(v []float64) *FloatEncoder { return NewFloatEncoder(v).WithFastLanes() }, ModeFloatFastLanes, 2},
+		{"precision", func(v []float64) *FloatEncoder { return NewFloatEncoder(v).WithPrecision(4) }, ModeFloat, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := tt.encoder(chunk(0)).Encode()
+			b, _ := tt.encoder(chunk(40)).Encode()
+
+			joined, err := Concat(a, b)
+			if err != nil {
+				t.Fatalf("concat error: %v", err)
+			}
+			info, err := Inspect(joined)
+			if err != nil {
+				t.Fatalf("inspect error: %v", err)
+			}
+			if info.Mode != tt.mode || info.ALPExponent != tt.exponent {
+				t.Errorf("expected %v with exponent %d, got %v with exponent %d", tt.mode, tt.exponent, info.Mode, info.ALPExponent)
+			}
+
+			decoded, err := NewDecoder(joined).DecodeFloat()
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if !slices.Equal(decoded, append(chunk(0), chunk(40)...)) {
+				t.Error("decoded values differ from the concatenation")
+			}
+		})
+	}
+
+	// Int blocks keep their coding too
+	ints := func(offset int64) []int64 {
+		return []int64{offset, offset + 60, offset + 121, offset + 180, offset + 240}
+	}
+	a, _ := NewIntEncoder(ints(0)).WithFastLanes().Encode()
+	b, _ := NewIntEncoder(ints(300)).WithFastLanes().Encode()
+	joined, err := Concat(a, b)
+	if err != nil {
+		t.Fatalf("concat error: %v", err)
+	}
+	if info, _ := Inspect(joined); info.Mode != ModeIntFastLanes {
+		t.Errorf("expected %v, got %v", ModeIntFastLanes, info.Mode)
+	}
+
+	// Mixed codings fall back to the defaults
+	c, _ := NewIntEncoder(ints(300)).WithPFOR().Encode()
+	joined, err = Concat(a, c)
+	if err != nil {
+		t.Fatalf("concat error: %v", err)
+	}
+	if info, _ := Inspect(joined); info.Mode != ModeInt {
+		t.Errorf("expected %v, got %v", ModeInt, info.Mode)
+	}
+}
+
+func TestConcat_Nullable(t *testing.T) {
+	a, _ := NewIntEncoder([]int64{10, 0, 30}).WithValidity([]bool{true, false, true}).WithChecksum().Encode()
+	b, _ := NewIntEncoder([]int64{40, 50}).Encode()
+
+	joined, err := Concat(a, b)
+	if err != nil {
+		t.Fatalf("concat error: %v", err)
+	}
+
+	values, valid, err := NewDecoder(joined).DecodeNullableInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(values, []int64{10, 0, 30, 40, 50}) || !slices.Equal(valid, []bool{true, false, true, true, true}) {
+		t.Errorf("unexpected values %v with validity %v", values, valid)
+	}
+	if err := Verify(joined); err != nil {
+		t.Errorf("verify error: %v", err)
+	}
+	if info, _ := Inspect(joined); !info.Checksum {
+		t.Error("expected the checksum to be kept")
+	}
+}
+
+func TestConcat_Columns(t *testing.T) {
+	a, _ := NewTimeSeriesEncoder([]int64{60, 120}, []float64{1.5, 2.5}).Encode()
+	b, _ := NewTimeSeriesEncoder([]int64{180, 240, 300}, []float64{3.5, 4.5, 5.5}).Encode()
+
+	joined, err := Concat(a, b)
+	if err != nil {
+		t.Fatalf("concat error: %v", err)
+	}
+	timestamps, values, err := NewDecoder(joined).DecodeTimeSeries()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(timestamps, []int64{60, 120, 180, 240, 300}) || !slices.Equal(values, []float64{1.5, 2.5, 3.5, 4.5, 5.5}) {
+		t.Errorf("unexpected time series %v, %v", timestamps, values)
+	}
+
+	frameA, _ := NewFrameEncoder().
+		AddInt("status", NewIntEncoder([]int64{200, 200})).
+		AddFloat("latency", NewFloatEncoder([]float64{0.25, 0.5})).
+		Encode()
+	frameB, _ := NewFrameEncoder().
+		AddInt("status", NewIntEncoder([]int64{404})).
+		AddFloat("latency", NewFloatEncoder([]float64{0.75})).
+		Encode()
+
+	joined, err = Concat(frameA, frameB)
+	if err != nil {
+		t.Fatalf("concat error: %v", err)
+	}
+	column, err := NewDecoder(joined).Column("latency")
+	if err != nil {
+		t.Fatalf("column error: %v", err)
+	}
+	if latency, err := column.DecodeFloat(); err != nil || !slices.Equal(latency, []float64{0.25, 0.5, 0.75}) {
+		t.Errorf("unexpected latency %v (%v)", latency, err)
+	}
+}
+
+func TestConcat_Invalid(t *testing.T) {
+	floats, _ := NewFloatEncoder([]float64{1.5, 2.5}).Encode()
+	ints, _ := NewIntEncoder([]int64{1, 2}).Encode()
+	timeSeries, _ := NewTimeSeriesEncoder([]int64{1, 2}, []float64{1.5, 2.5}).Encode()
+	frame, _ := NewFrameEncoder().AddInt("a", NewIntEncoder([]int64{1, 2})).Encode()
+	renamed, _ := NewFrameEncoder().AddInt("b", NewIntEncoder([]int64{1, 2})).Encode()
+	retyped, _ := NewFrameEncoder().AddFloat("a", NewFloatEncoder([]float64{1, 2})).Encode()
+
+	tests := []struct {
+		name string
+		a, b []byte
+	}{
+		{"kinds", floats, ints},
+		{"time series and float", timeSeries, floats},
+		{"column names", frame, renamed},
+		{"column kinds", frame, retyped},
+		{"garbage", floats, []byte("garbage")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Concat(tt.a, tt.b); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package alpine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// FrameEncoder is a builder for encoding named columns of the same length into
+// one blob. Each column is encoded by its own FloatEncoder or IntEncoder, so it
+// picks its mode and parameters independently of the others.
+type FrameEncoder struct {
+	columns  []frameColumn
+	checksum bool
+}
+
+// frameColumn is one column added to a FrameEncoder
+type frameColumn struct {
+	name   string
+	length int
+	encode func() (*internal.Header, []byte, error)
+}
+
+// NewFrameEncoder creates a new FrameEncoder without columns
+func NewFrameEncoder() *FrameEncoder {
+	return &FrameEncoder{}
+}
+
+// AddFloat adds a float64 column encoded by enc. The options of enc apply to
+// the column, except WithChecksum, which is set for the whole frame.
+func (e *FrameEncoder) AddFloat(name string, enc *FloatEncoder) *FrameEncoder {
+	e.columns = append(e.columns, newFrameColumn(name, enc.data, enc.valid, enc.encode))
+	return e
+}
+
+// AddInt adds an int64 column encoded by enc. The options of enc apply to the
+// column, except WithChecksum, which is set for the whole frame.
+func (e *FrameEncoder) AddInt(name string, enc *IntEncoder) *FrameEncoder {
+	e.columns = append(e.columns, newFrameColumn(name, enc.data, enc.valid, enc.encode))
+	return e
+}
+
+// newFrameColumn defers encoding data with encode until the frame is encoded.
+// Frames have no validity sections, so a column with nulls fails then.
+func newFrameColumn[T any](name string, data []T, valid []bool, encode func([]T) (*internal.Header, []byte, error)) frameColumn {
+	return frameColumn{
+		name:   name,
+		length: len(data),
+		encode: func() (*internal.Header, []byte, error) {
+			present, validity, err := splitNulls(data, valid)
+			if err != nil {
+				return nil, nil, err
+			}
+			if validity != nil {
+				return nil, nil, errors.New("frame columns cannot hold null values")
+			}
+			return encode(present)
+		},
+	}
+}
+
+// WithChecksum appends a CRC32C checksum over the header and payload, which
+// the decoder and Verify check before trusting the data
+func (e *FrameEncoder) WithChecksum() *FrameEncoder {
+	e.checksum = true
+	return e
+}
+
+// Encode compresses every column and returns the encoded bytes. All columns
+// must have the same length and distinct names.
+func (e *FrameEncoder) Encode() ([]byte, error) {
+	n := 0
+	if len(e.columns) > 0 {
+		n = e.columns[0].length
+	}
+
+	names := make([]string, len(e.columns))
+	headers := make([]*internal.Header, len(e.columns))
+	payloads := make([][]byte, len(e.columns))
+	for i, c := range e.columns {
+		if slices.Contains(names[:i], c.name) {
+			return nil, fmt.Errorf("duplicate column name %q", c.name)
+		}
+		if c.length != n {
+			return nil, fmt.Errorf("column %q: %d values, expected %d", c.name, c.length, n)
+		}
+
+		header, payload, err := c.encode()
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", c.name, err)
+		}
+		names[i], headers[i], payloads[i] = c.name, header, payload
+	}
+
+	header := &internal.Header{
+		Mode:       internal.ModeFrame,
+		ValueCount: n,
+	}
+
+	return marshalBlob(header, internal.MarshalFrame(names, headers, payloads), e.checksum), nil
+}
+
+// Columns returns the column names of a frame, in the order they were added
+func (d *Decoder) Columns() ([]string, error) {
+	_, names, err := d.frameIndex()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(names), nil
+}
+
+// Column returns a decoder for the named column of a frame. Only the column
+// directory is parsed; the other columns are not decoded.
+func (d *Decoder) Column(name string) (*Decoder, error) {
+	columns, names, err := d.frameIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.Index(names, name)
+	if i < 0 {
+		return nil, fmt.Errorf("no column named %q", name)
+	}
+	return columns[i], nil
+}
+
+// frameIndex is columnIndex for the APIs that address columns by name
+func (d *Decoder) frameIndex() ([]*Decoder, []string, error) {
+	header, _, err := d.blockIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Mode != internal.ModeFrame {
+		return nil, nil, fmt.Errorf("expected ModeFrame, got %v", Mode(header.Mode))
+	}
+	return d.columnIndex()
+}
+
+// columnIndex parses the column directory of a ModeTimeSeries or ModeFrame
+// blob once and returns a decoder per column, each with its block index
+// already parsed, along with the column names of a frame
+func (d *Decoder) columnIndex() ([]*Decoder, []string, error) {
+	d.columnsOnce.Do(func() {
+		d.columnsErr = d.parseColumns()
+	})
+	if d.columnsErr != nil {
+		return nil, nil, d.columnsErr
+	}
+	return d.columns, d.names, nil
+}
+
+// parseColumns fills the caches of columnIndex
+func (d *Decoder) parseColumns() error {
+	header, blocks, err := d.denseIndex()
+	if err != nil {
+		return err
+	}
+
+	var columns []internal.Column
+	var names []string
+	switch header.Mode {
+	case internal.ModeTimeSeries:
+		columns, err = internal.UnmarshalColumns(blocks[0].Payload, header.ValueCount)
+		if err == nil && len(columns) != 2 {
+			err = fmt.Errorf("expected 2 columns, got %d", len(columns))
+		}
+	case internal.ModeFrame:
+		names, columns, err = internal.UnmarshalFrame(blocks[0].Payload, header.ValueCount)
+	default:
+		return fmt.Errorf("expected a column mode, got %v", Mode(header.Mode))
+	}
+	if err != nil {
+		return fmt.Errorf("unmarshal columns: %w", err)
+	}
+
+	decoders := make([]*Decoder, len(columns))
+	for i, c := range columns {
+		blocks, err := segmentBlocks(c.Header, c.Payload)
+		if err != nil {
+			return fmt.Errorf("column %d: %w", i, err)
+		}
+		decoders[i] = &Decoder{encoded: c.Payload, header: c.Header, blocks: blocks}
+	}
+
+	d.columns, d.names = decoders, names
+	return nil
+}
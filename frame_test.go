@@ -0,0 +1,147 @@
+package alpine
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFrameEncoder_RoundTrip(t *testing.T) {
+	n := 300
+	user := make([]float64, n)
+	system := make([]float64, n)
+	noise := make([]float64, n)
+	ticks := make([]int64, n)
+	for i := range n {
+		user[i] = 12.5 + float64(i%9)*0.25
+		system[i] = 3.1 + float64(i%4)*0.1
+		noise[i] = math.Sin(float64(i)) * 1e-3
+		ticks[i] = int64(i) * 100
+	}
+
+	encoded, err := NewFrameEncoder().
+		AddFloat("cpu.user", NewFloatEncoder(user)).
+		AddFloat("cpu.system", NewFloatEncoder(system).WithBlockSize(64)).
+		AddFloat("noise", NewFloatEncoder(noise)).
+		AddInt("ticks", NewIntEncoder(ticks)).
+		WithChecksum().
+		Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	d := NewDecoder(encoded)
+	names, err := d.Columns()
+	if err != nil {
+		t.Fatalf("columns error: %v", err)
+	}
+	if want := []string{"cpu.user", "cpu.system", "noise", "ticks"}; !slices.Equal(names, want) {
+		t.Errorf("expected columns %q, got %q", want, names)
+	}
+
+	for name, want := range map[string][]float64{"cpu.user": user, "cpu.system": system, "noise": noise} {
+		column, err := d.Column(name)
+		if err != nil {
+			t.Fatalf("column %q: %v", name, err)
+		}
+		got, err := column.DecodeFloat()
+		if err != nil {
+			t.Fatalf("column %q: decode error: %v", name, err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("column %q: values differ", name)
+		}
+	}
+
+	column, err := d.Column("ticks")
+	if err != nil {
+		t.Fatalf("column ticks: %v", err)
+	}
+	if v, err := column.IntAt(250); err != nil || v != 25000 {
+		t.Errorf("ticks[250]: expected 25000, got %d (%v)", v, err)
+	}
+
+	// Each column picks its own mode
+	info, err := Inspect(encoded)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	modes := make(map[string]Mode)
+	for _, c := range info.Columns {
+		modes[c.Name] = c.Mode
+	}
+	if modes["cpu.user"] != ModeFloat || modes["cpu.system"] != ModeBlocked || modes["ticks"] != ModeInt || modes["noise"] == ModeFloat {
+		t.Errorf("unexpected column modes %v", modes)
+	}
+
+	if err := Verify(encoded); err != nil {
+		t.Errorf("verify error: %v", err)
+	}
+}
+
+func TestFrameEncoder_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  *FrameEncoder
+	}{
+		{"length mismatch", NewFrameEncoder().
+			AddFloat("a", NewFloatEncoder([]float64{1, 2, 3})).
+			AddInt("b", NewIntEncoder([]int64{1, 2}))},
+		{"duplicate name", NewFrameEncoder().
+			AddFloat("a", NewFloatEncoder([]float64{1, 2})).
+			AddInt("a", NewIntEncoder([]int64{1, 2}))},
+		{"nulls", NewFrameEncoder().
+			AddInt("a", NewIntEncoder([]int64{1, 2}).WithValidity([]bool{true, false}))},
+		{"column option", NewFrameEncoder().
+			AddInt("a", NewIntEncoder([]int64{1, 2}).WithBlockSize(1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.enc.Encode(); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDecoder_Frame(t *testing.T) {
+	encoded, err := NewFrameEncoder().
+		AddFloat("temp", NewFloatEncoder([]float64{21.5, 21.6})).
+		Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	d := NewDecoder(encoded)
+	if kind, err := d.Kind(); err != nil || kind != KindFrame {
+		t.Errorf("expected KindFrame, got %v (%v)", kind, err)
+	}
+	if _, err := d.Column("missing"); err == nil {
+		t.Error("expected error for a missing column, got nil")
+	}
+	if _, err := d.Decode(); err == nil {
+		t.Error("expected Decode to reject a frame, got nil")
+	}
+	if _, err := d.DecodeFloat(); err == nil {
+		t.Error("expected DecodeFloat to reject a frame, got nil")
+	}
+
+	plain, _ := NewFloatEncoder([]float64{21.5, 21.6}).Encode()
+	if _, err := NewDecoder(plain).Column("temp"); err == nil {
+		t.Error("expected Column to reject a float blob, got nil")
+	}
+
+	timeSeries, _ := NewTimeSeriesEncoder([]int64{1, 2}, []float64{21.5, 21.6}).Encode()
+	if _, err := NewDecoder(timeSeries).Columns(); err == nil {
+		t.Error("expected Columns to reject a time series, got nil")
+	}
+
+	empty, err := NewFrameEncoder().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	if names, err := NewDecoder(empty).Columns(); err != nil || len(names) != 0 {
+		t.Errorf("expected no columns, got %q (%v)", names, err)
+	}
+}
@@ -0,0 +1,172 @@
+package alpine
+
+import (
+	"fmt"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// Info describes an encoded blob without decoding its values
+type Info struct {
+	Kind        Kind
+	Mode        Mode
+	RiceParam   int // 0 for modes without a Golomb-Rice payload
+	ALPExponent int // ALP-scaled modes only: ModeFloat, ModeFloatPFOR, ModeFloatFastLanes
+	ALPFactor   int // ALP-scaled modes only
+	ValueCount  int // nulls included
+	NullCount   int
+	Checksum    bool
+
+	HeaderBytes  int
+	PayloadBytes int
+	TotalBytes   int
+
+	// BitsPerValue is the encoded size per value, header included
+	BitsPerValue float64
+	// CompressionRatio is the raw size (8 bytes per value) over the encoded size
+	CompressionRatio float64
+
+	// Blocks describes each block of a ModeBlocked blob; nil otherwise
+	Blocks []BlockInfo
+
+	// Columns describes each column of a ModeTimeSeries or ModeFrame blob; nil
+	// otherwise
+	Columns []ColumnInfo
+}
+
+// ColumnInfo describes one column of a ModeTimeSeries or ModeFrame blob
+type ColumnInfo struct {
+	Name         string // ModeFrame only
+	Kind         Kind
+	Mode         Mode
+	RiceParam    int
+	ALPExponent  int // ALP-scaled modes only
+	ALPFactor    int // ALP-scaled modes only
+	PayloadBytes int
+	BitsPerValue float64
+
+	// Blocks describes each block of a ModeBlocked column; nil otherwise
+	Blocks []BlockInfo
+}
+
+// BlockInfo describes one block of a ModeBlocked blob
+type BlockInfo struct {
+	Mode         Mode
+	RiceParam    int
+	ALPExponent  int // ALP-scaled modes only
+	ALPFactor    int // ALP-scaled modes only
+	Start        int // index of the block's first value in the series
+	ValueCount   int
+	PayloadBytes int
+	BitsPerValue float64
+}
+
+// Inspect reports the header fields and compression statistics of an encoded
+// blob. Only the header and block index are parsed; values are not decoded.
+func Inspect(encoded []byte) (*Info, error) {
+	d := NewDecoder(encoded)
+
+	header, payload, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := d.Kind()
+	if err != nil {
+		return nil, err
+	}
+
+	// The header counts only the present values of a nullable series
+	valueCount := header.ValueCount
+	if d.valid != nil {
+		valueCount = len(d.valid)
+	}
+
+	checksum := header.Flags&internal.FlagChecksum != 0
+	trailer := 0
+	if checksum {
+		trailer = internal.ChecksumSize
+	}
+
+	info := &Info{
+		Kind:         kind,
+		Mode:         Mode(header.Mode),
+		RiceParam:    header.RiceParam,
+		ALPExponent:  header.ALPExp,
+		ALPFactor:    header.ALPFactor,
+		ValueCount:   valueCount,
+		NullCount:    valueCount - header.ValueCount,
+		Checksum:     checksum,
+		HeaderBytes:  len(encoded) - len(payload) - trailer,
+		PayloadBytes: len(payload),
+		TotalBytes:   len(encoded),
+	}
+
+	if valueCount > 0 {
+		info.BitsPerValue = float64(8*len(encoded)) / float64(valueCount)
+		info.CompressionRatio = float64(8*valueCount) / float64(len(encoded))
+	}
+
+	switch header.Mode {
+	case internal.ModeBlocked:
+		_, blocks, err := d.blockIndex()
+		if err != nil {
+			return nil, fmt.Errorf("block index: %w", err)
+		}
+		info.Blocks = blockInfos(blocks)
+
+	case internal.ModeTimeSeries, internal.ModeFrame:
+		columns, names, err := d.columnIndex()
+		if err != nil {
+			return nil, fmt.Errorf("column directory: %w", err)
+		}
+
+		info.Columns = make([]ColumnInfo, len(columns))
+		for i, c := range columns {
+			kind, err := c.Kind()
+			if err != nil {
+				return nil, fmt.Errorf("column %d: %w", i, err)
+			}
+
+			payloadBytes := len(c.encoded)
+
+			info.Columns[i] = ColumnInfo{
+				Kind:         kind,
+				Mode:         Mode(c.header.Mode),
+				RiceParam:    c.header.RiceParam,
+				ALPExponent:  c.header.ALPExp,
+				ALPFactor:    c.header.ALPFactor,
+				PayloadBytes: payloadBytes,
+			}
+			if valueCount > 0 {
+				info.Columns[i].BitsPerValue = float64(8*payloadBytes) / float64(valueCount)
+			}
+			if names != nil {
+				info.Columns[i].Name = names[i]
+			}
+			if c.header.Mode == internal.ModeBlocked {
+				info.Columns[i].Blocks = blockInfos(c.blocks)
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// blockInfos describes each block of a ModeBlocked series
+func blockInfos(blocks []internal.Block) []BlockInfo {
+	infos := make([]BlockInfo, len(blocks))
+	for i, b := range blocks {
+		infos[i] = BlockInfo{
+			Mode:         Mode(b.Header.Mode),
+			RiceParam:    b.Header.RiceParam,
+			ALPExponent:  b.Header.ALPExp,
+			ALPFactor:    b.Header.ALPFactor,
+			Start:        b.Start,
+			ValueCount:   b.Header.ValueCount,
+			PayloadBytes: len(b.Payload),
+			BitsPerValue: float64(8*len(b.Payload)) / float64(b.Header.ValueCount),
+		}
+	}
+	return infos
+}
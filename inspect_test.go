@@ -0,0 +1,116 @@
+package alpine
+
+import (
+	"testing"
+
+	"github.com/ach968/alpine/internal"
+)
+
+func TestInspect_SingleSegment(t *testing.T) {
+	input := []float64{21.35, 21.40, 21.38, 21.41, 21.45, 21.50}
+
+	encoded, err := NewFloatEncoder(input).WithRiceParam(4).WithChecksum().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	info, err := Inspect(encoded)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+
+	if info.Kind != KindFloat || info.Mode != ModeFloat {
+		t.Errorf("expected float ModeFloat, got %v %v", info.Kind, info.Mode)
+	}
+	if info.RiceParam != 4 || info.ALPExponent != 2 || info.ValueCount != len(input) || !info.Checksum {
+		t.Errorf("unexpected header fields: %+v", info)
+	}
+	if info.HeaderBytes+info.PayloadBytes+internal.ChecksumSize != len(encoded) || info.TotalBytes != len(encoded) {
+		t.Errorf("sizes don't add up to %d: %+v", len(encoded), info)
+	}
+	if info.BitsPerValue != float64(8*len(encoded))/float64(len(input)) {
+		t.Errorf("unexpected bits per value %v", info.BitsPerValue)
+	}
+	if info.CompressionRatio != float64(8*len(input))/float64(len(encoded)) {
+		t.Errorf("unexpected compression ratio %v", info.CompressionRatio)
+	}
+	if info.Blocks != nil {
+		t.Errorf("expected no block details, got %v", info.Blocks)
+	}
+}
+
+func TestInspect_Blocks(t *testing.T) {
+	input := make([]int64, 2500)
+	for i := range input {
+		input[i] = int64(i * 60)
+		if i >= 1000 {
+			input[i] += int64(i*7919) % 500
+		}
+	}
+
+	encoded, err := NewIntEncoder(input).WithBlockSize(1000).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	info, err := Inspect(encoded)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+
+	if info.Kind != KindInt || info.Mode != ModeBlocked || info.ValueCount != len(input) {
+		t.Fatalf("unexpected summary: %+v", info)
+	}
+	if len(info.Blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(info.Blocks))
+	}
+
+	// The noise steps by a constant modulo 500, so most residuals are 0 and
+	// the wrap-arounds are escaped: bit-packing the noisy blocks is smaller
+	starts := []int{0, 1000, 2000}
+	modes := []Mode{ModeInt, ModeIntFOR, ModeIntFOR}
+	payload := 0
+	for i, b := range info.Blocks {
+		if b.Mode != modes[i] || b.Start != starts[i] || (b.Mode == ModeInt) != (b.RiceParam > 0) {
+			t.Errorf("block %d: unexpected details %+v", i, b)
+		}
+		payload += b.PayloadBytes
+	}
+
+	// Constant steps cost next to nothing, the noisy blocks take many more bits
+	if info.Blocks[0].BitsPerValue >= info.Blocks[1].BitsPerValue {
+		t.Errorf("expected the regular block to be cheaper: %+v", info.Blocks)
+	}
+	if payload >= info.PayloadBytes {
+		t.Errorf("block payloads (%d bytes) should fit in the payload (%d bytes) with the index", payload, info.PayloadBytes)
+	}
+}
+
+func TestInspect_Invalid(t *testing.T) {
+	if _, err := Inspect([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error for invalid data, got nil")
+	}
+}
+
+func TestModeConstants_MatchFormat(t *testing.T) {
+	modes := map[Mode]internal.Mode{
+		ModeFloat:          internal.ModeFloat,
+		ModeInt:            internal.ModeInt,
+		ModeFloatXOR:       internal.ModeFloatXOR,
+		ModeFloatRD:        internal.ModeFloatRD,
+		ModeBlocked:        internal.ModeBlocked,
+		ModeTimeSeries:     internal.ModeTimeSeries,
+		ModeFrame:          internal.ModeFrame,
+		ModeIntFOR:         internal.ModeIntFOR,
+		ModeIntPFOR:        internal.ModeIntPFOR,
+		ModeFloatPFOR:      internal.ModeFloatPFOR,
+		ModeIntFastLanes:   internal.ModeIntFastLanes,
+		ModeFloatFastLanes: internal.ModeFloatFastLanes,
+	}
+
+	for public, format := range modes {
+		if int(public) != int(format) {
+			t.Errorf("%v: expected %d, got %d", public, format, public)
+		}
+	}
+}
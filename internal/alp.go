@@ -1,85 +1,228 @@
 package internal
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"math"
+	"math/bits"
+	"slices"
 )
 
-var pow10Table [18]float64
+// MaxALPExponent is the largest supported ALP exponent or factor
+const MaxALPExponent = 17
+
+var pow10Table [MaxALPExponent + 1]float64
+
+// Rough cost, in bits, of storing one exception (position + raw bits). Used to
+// weigh exceptions against wider deltas when choosing an exponent.
+const exceptionCostBits = 80
+
+// Maximum number of values inspected when estimating the cost of an exponent,
+// and the size of the probe used to shortlist candidates for the full sample.
+const (
+	precisionSampleSize = 1024
+	precisionProbeSize  = 32
+	precisionCandidates = 4
+)
 
 func init() {
 	pow10Table[0] = 1
-	for i := 1; i <= 17; i++ {
+	for i := 1; i <= MaxALPExponent; i++ {
 		pow10Table[i] = pow10Table[i-1] * 10
 	}
 }
 
-func ALPEncode(input []float64, exponent int) ([]int64, int, error) {
+// ALPEncode scales input by 10^exponent / 10^factor into integers. The factor
+// divides out trailing zeros, so large round numbers such as 1.25e12 become
+// small integers. Values that do not round-trip are returned as exceptions, and
+// their slots are filled with a neighbouring value so they do not disturb the
+// delta predictor. A negative exponent selects both exponent and factor
+// automatically; an explicit exponent uses a factor of 0.
+func ALPEncode(input []float64, exponent int) ([]int64, int, int, []Exception, error) {
 	if len(input) == 0 {
-		return nil, 0, errors.New("input cannot be empty")
+		return nil, 0, 0, nil, errors.New("input cannot be empty")
 	}
 
+	factor := 0
 	if exponent < 0 {
-		exponent = detectPrecision(input)
+		exponent, factor = detectPrecision(input)
 	}
 
 	// Add bounds check
 	if exponent >= len(pow10Table) {
-		return nil, 0, fmt.Errorf("exponent %d exceeds maximum %d", exponent, len(pow10Table)-1)
+		return nil, 0, 0, nil, fmt.Errorf("exponent %d exceeds maximum %d", exponent, len(pow10Table)-1)
 	}
 
 	multiplier := pow10Table[exponent]
+	divisor := pow10Table[factor]
 	result := make([]int64, len(input))
+	var exceptions []Exception
 	for i, val := range input {
-		result[i] = int64(math.Round(val * multiplier))
+		scaled, ok := alpScale(val, multiplier, divisor)
+		if !ok {
+			exceptions = append(exceptions, Exception{Position: i, Bits: math.Float64bits(val)})
+			continue
+		}
+		result[i] = scaled
 	}
+	fillExceptions(result, exceptions)
 
-	return result, exponent, nil
+	return result, exponent, factor, exceptions, nil
 }
 
-func ALPDecode(input []int64, exponent int) []float64 {
-	if exponent < 0 || exponent >= len(pow10Table) {
+func ALPDecode(input []int64, exponent int, factor int) []float64 {
+	if exponent < 0 || exponent >= len(pow10Table) || factor < 0 || factor >= len(pow10Table) {
 		// Return empty or handle error - for now return empty slice
 		return []float64{}
 	}
 	multiplier := pow10Table[exponent]
+	divisor := pow10Table[factor]
 	result := make([]float64, len(input))
 	for i, val := range input {
-		result[i] = float64(val) / multiplier
+		result[i] = float64(val) * divisor / multiplier
 	}
 	return result
 }
 
-func detectPrecision(data []float64) int {
-	const maxExp = 17
+// ALPScale scales val like ALPEncode at the given exponent and factor, and
+// reports whether ALPDecode restores it bit for bit. Both must be at most
+// MaxALPExponent.
+func ALPScale(val float64, exponent, factor int) (int64, bool) {
+	return alpScale(val, pow10Table[exponent], pow10Table[factor])
+}
+
+// alpScale converts val into an integer and reports whether ALPDecode restores
+// val bit for bit. NaN, ±Inf and -0.0 never do, so they always end up as
+// exceptions with their exact bit pattern.
+func alpScale(val, multiplier, divisor float64) (int64, bool) {
 	const maxInt64 = float64(math.MaxInt64)
 
-	for p := 1; p <= maxExp; p++ {
-		multiplier := pow10Table[p]
-		allMatch := true
-		for _, val := range data {
-			scaled := val * multiplier
-			if scaled > maxInt64 || scaled < -maxInt64 {
-				allMatch = false
-				break
-			}
-			rounded := math.Round(scaled)
-			// Check if rounding changes the value meaningfully, slight speed optimization
-			if math.Abs(scaled-rounded) >= 0.5 {
-				allMatch = false
-				break
-			}
-			// Verify round-trip
-			restored := rounded / multiplier
-			if restored != val {
-				allMatch = false
-				break
+	scaled := val * multiplier / divisor
+	// Written so that NaN also fails the range check
+	if !(scaled > -maxInt64 && scaled < maxInt64) {
+		return 0, false
+	}
+	encoded := int64(math.Round(scaled))
+	// Compare bits of what ALPDecode will produce: -0.0 == 0.0 as values,
+	// but the integer path drops the sign
+	if math.Float64bits(float64(encoded)*divisor/multiplier) != math.Float64bits(val) {
+		return 0, false
+	}
+	return encoded, true
+}
+
+// fillExceptions overwrites exception slots with the previous encoded value, or
+// with the first regular value for leading exceptions.
+func fillExceptions(scaled []int64, exceptions []Exception) {
+	lead := 0
+	for lead < len(exceptions) && exceptions[lead].Position == lead {
+		lead++
+	}
+	var fill int64
+	if lead < len(scaled) {
+		fill = scaled[lead]
+	}
+	for _, exc := range exceptions {
+		if exc.Position < lead {
+			scaled[exc.Position] = fill
+		} else {
+			scaled[exc.Position] = scaled[exc.Position-1]
+		}
+	}
+}
+
+// detectPrecision picks the exponent and factor with the lowest estimated
+// encoded size, trading exceptions against the width of the resulting deltas.
+// As in ALP, the factor never exceeds the exponent, except for exponent 0 where
+// it divides out trailing zeros of large round numbers. Every pair is scored on
+// a small probe first, and only the most promising ones on the full sample.
+// Ties go to the pair found first.
+func detectPrecision(data []float64) (int, int) {
+	sample := sampleValues(data, precisionSampleSize)
+	probe := sampleValues(sample, precisionProbeSize)
+
+	type candidate struct {
+		exp, factor int
+		cost        uint64
+	}
+	var candidates []candidate
+	for e := 1; e <= MaxALPExponent; e++ {
+		for f := 0; f <= e; f++ {
+			candidates = append(candidates, candidate{exp: e, factor: f})
+		}
+	}
+	for f := 0; f <= MaxALPExponent; f++ {
+		candidates = append(candidates, candidate{exp: 0, factor: f})
+	}
+
+	// Scoring stops once a pair is worse than the best so far, which is all
+	// the shortlist needs to know about it
+	probeBest := uint64(math.MaxUint64)
+	for i := range candidates {
+		c := &candidates[i]
+		c.cost = estimateALPCost(probe, pow10Table[c.exp], pow10Table[c.factor], probeBest)
+		probeBest = min(probeBest, c.cost)
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(a.cost, b.cost)
+	})
+
+	if len(probe) == len(sample) {
+		return candidates[0].exp, candidates[0].factor
+	}
+
+	best, bestCost := candidates[0], uint64(math.MaxUint64)
+	for _, c := range candidates[:precisionCandidates] {
+		cost := estimateALPCost(sample, pow10Table[c.exp], pow10Table[c.factor], bestCost)
+		if cost < bestCost {
+			best, bestCost = c, cost
+		}
+	}
+
+	return best.exp, best.factor
+}
+
+// sampleValues returns data itself when it has at most size values, otherwise
+// an evenly strided sample of size values
+func sampleValues(data []float64, size int) []float64 {
+	if len(data) <= size {
+		return data
+	}
+	sample := make([]float64, size)
+	step := float64(len(data)) / float64(size)
+	for i := range sample {
+		sample[i] = data[int(float64(i)*step)]
+	}
+	return sample
+}
+
+// estimateALPCost approximates the number of bits needed to encode data at the
+// given multiplier and divisor: the significant bits of each zigzagged
+// predictive delta plus a fixed cost per exception. It stops early once the
+// cost reaches limit.
+func estimateALPCost(data []float64, multiplier, divisor float64, limit uint64) uint64 {
+	var cost uint64
+	var prev1, prev2 int64
+	seen := 0
+	for _, val := range data {
+		if cost >= limit {
+			return cost
+		}
+		scaled, ok := alpScale(val, multiplier, divisor)
+		if !ok {
+			cost += exceptionCostBits
+			if seen == 0 {
+				continue
 			}
+			scaled = prev1
 		}
-		if allMatch {
-			return p
+		if seen >= 2 {
+			delta := scaled - (prev1 + (prev1 - prev2))
+			cost += uint64(bits.Len64(uint64(delta<<1^delta>>63))) + 1
 		}
+		prev2, prev1 = prev1, scaled
+		seen++
 	}
-	return 0
+	return cost
 }
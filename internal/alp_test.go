@@ -1,12 +1,13 @@
 package internal
 
 import (
+	"math"
 	"testing"
 )
 
 func TestALPEncode_Basic(t *testing.T) {
 	input := []float64{1.0, 2.0, 3.0}
-	scaled, exponent, err := ALPEncode(input, 0)
+	scaled, exponent, _, _, err := ALPEncode(input, 0)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -30,7 +31,7 @@ func TestALPEncode_Basic(t *testing.T) {
 
 func TestALPEncode_Decimals(t *testing.T) {
 	input := []float64{1.5, 2.5, 3.5}
-	scaled, exponent, err := ALPEncode(input, -1)
+	scaled, exponent, _, _, err := ALPEncode(input, -1)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -52,7 +53,7 @@ func TestALPDecode(t *testing.T) {
 	input := []int64{15, 25, 35}
 	exponent := 1
 
-	result := ALPDecode(input, exponent)
+	result := ALPDecode(input, exponent, 0)
 
 	expected := []float64{1.5, 2.5, 3.5}
 	if len(result) != len(expected) {
@@ -69,12 +70,12 @@ func TestALPDecode(t *testing.T) {
 func TestALP_RoundTrip(t *testing.T) {
 	original := []float64{3.14159, 2.71828, 1.41421}
 
-	scaled, exponent, err := ALPEncode(original, -1)
+	scaled, exponent, _, _, err := ALPEncode(original, -1)
 	if err != nil {
 		t.Fatalf("encode error: %v", err)
 	}
 
-	decoded := ALPDecode(scaled, exponent)
+	decoded := ALPDecode(scaled, exponent, 0)
 
 	if len(decoded) != len(original) {
 		t.Fatalf("length mismatch: expected %d, got %d", len(original), len(decoded))
@@ -90,7 +91,7 @@ func TestALP_RoundTrip(t *testing.T) {
 }
 
 func TestALPEncode_Empty(t *testing.T) {
-	_, _, err := ALPEncode([]float64{}, 0)
+	_, _, _, _, err := ALPEncode([]float64{}, 0)
 	if err == nil {
 		t.Error("expected error for empty input, got nil")
 	}
@@ -98,7 +99,7 @@ func TestALPEncode_Empty(t *testing.T) {
 
 func TestALPEncode_Negative(t *testing.T) {
 	input := []float64{-1.5, -2.5, -3.5}
-	scaled, exponent, err := ALPEncode(input, -1)
+	scaled, exponent, _, _, err := ALPEncode(input, -1)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -118,7 +119,7 @@ func TestALPEncode_Negative(t *testing.T) {
 
 func TestALPEncode_SingleElement(t *testing.T) {
 	input := []float64{42.0}
-	scaled, exponent, err := ALPEncode(input, 0)
+	scaled, exponent, _, _, err := ALPEncode(input, 0)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -148,7 +149,7 @@ func TestDetectPrecision(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, exponent, err := ALPEncode(tt.input, -1) // Use -1 to trigger auto-detection
+			_, exponent, _, _, err := ALPEncode(tt.input, -1) // Use -1 to trigger auto-detection
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -164,20 +165,198 @@ func FuzzALP_RoundTrip(f *testing.F) {
 	f.Fuzz(func(t *testing.T, a, b, c float64) {
 		input := []float64{a, b, c}
 
-		scaled, exponent, err := ALPEncode(input, -1)
+		scaled, exponent, factor, exceptions, err := ALPEncode(input, -1)
 		if err != nil {
 			return // Some values may be invalid
 		}
 
-		decoded := ALPDecode(scaled, exponent)
+		decoded := ALPDecode(scaled, exponent, factor)
+		PatchExceptions(decoded, exceptions)
 
-		// Verify round-trip only if precision detection found a suitable precision
-		// Some values may not have a lossless ALP representation
+		// Values without a lossless ALP representation come back as exceptions,
+		// so the round trip must be bit-exact, including NaN payloads and -0.0
 		for i := range input {
-			if decoded[i] != input[i] {
-				// Skip values that can't be losslessly encoded
-				return
+			if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+				t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
 			}
 		}
 	})
 }
+
+func TestALPEncode_Exceptions(t *testing.T) {
+	// One calibrated value with 15 significant digits among two-decimal readings
+	input := []float64{21.35, 21.40, 21.38, 21.3987654321012, 21.41, 21.45}
+
+	scaled, exponent, factor, exceptions, err := ALPEncode(input, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if exponent != 2 {
+		t.Errorf("expected exponent 2, got %d", exponent)
+	}
+
+	if len(exceptions) != 1 || exceptions[0].Position != 3 {
+		t.Fatalf("expected a single exception at position 3, got %v", exceptions)
+	}
+
+	// The exception slot is filled with its predecessor to keep deltas small
+	if scaled[3] != scaled[2] {
+		t.Errorf("expected exception slot filled with %d, got %d", scaled[2], scaled[3])
+	}
+
+	decoded := ALPDecode(scaled, exponent, factor)
+	PatchExceptions(decoded, exceptions)
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestALPEncode_ExplicitExponentExceptions(t *testing.T) {
+	input := []float64{1.5, 2.25, 3.125}
+
+	scaled, _, _, exceptions, err := ALPEncode(input, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(exceptions) != 2 {
+		t.Fatalf("expected 2 exceptions, got %d", len(exceptions))
+	}
+
+	decoded := ALPDecode(scaled, 1, 0)
+	PatchExceptions(decoded, exceptions)
+	for i := range input {
+		if decoded[i] != input[i] {
+			t.Errorf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestALPEncode_LeadingExceptions(t *testing.T) {
+	input := []float64{1e300, 1e300, 4.5, 5.5}
+
+	scaled, _, _, exceptions, err := ALPEncode(input, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(exceptions) != 2 {
+		t.Fatalf("expected 2 exceptions, got %d", len(exceptions))
+	}
+
+	// Leading exceptions take the first regular value
+	if scaled[0] != 45 || scaled[1] != 45 {
+		t.Errorf("expected leading slots filled with 45, got %v", scaled[:2])
+	}
+}
+
+func TestALPEncode_SpecialValues(t *testing.T) {
+	specials := []float64{
+		math.NaN(),
+		math.Float64frombits(0x7FF0000000000002), // Prometheus stale marker
+		math.Float64frombits(0x7FF0000000000001), // signalling NaN
+		math.Float64frombits(0xFFF8000000000BAD), // negative NaN with payload
+		math.Inf(1),
+		math.Inf(-1),
+		math.Copysign(0, -1),
+	}
+
+	input := []float64{1.25, 1.5}
+	input = append(input, specials...)
+	input = append(input, 1.75, 2.0)
+
+	scaled, exponent, factor, exceptions, err := ALPEncode(input, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Special values must not influence the chosen exponent
+	if exponent != 2 {
+		t.Errorf("expected exponent 2, got %d", exponent)
+	}
+
+	if len(exceptions) != len(specials) {
+		t.Fatalf("expected %d exceptions, got %d", len(specials), len(exceptions))
+	}
+
+	// Exception slots repeat the last regular value, keeping deltas at zero
+	for _, exc := range exceptions {
+		if scaled[exc.Position] != 150 {
+			t.Errorf("slot %d: expected fill 150, got %d", exc.Position, scaled[exc.Position])
+		}
+	}
+
+	decoded := ALPDecode(scaled, exponent, factor)
+	PatchExceptions(decoded, exceptions)
+	for i := range input {
+		if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+			t.Errorf("round-trip[%d]: expected %#x, got %#x", i, math.Float64bits(input[i]), math.Float64bits(decoded[i]))
+		}
+	}
+}
+
+func TestALPEncode_PositiveZero(t *testing.T) {
+	_, _, _, exceptions, err := ALPEncode([]float64{0, 1.5, 0}, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(exceptions) != 0 {
+		t.Errorf("expected +0.0 to encode without exceptions, got %v", exceptions)
+	}
+}
+
+func TestALPEncode_Factor(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []float64
+		expected []int64
+	}{
+		{"trillions", []float64{1.25e12, 1.26e12, 1.24e12, 1.3e12}, []int64{125, 126, 124, 130}},
+		{"round millions", []float64{340000000.0, 350000000.0, 330000000.0}, []int64{34, 35, 33}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaled, exponent, factor, exceptions, err := ALPEncode(tt.input, -1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if exponent != 0 || factor == 0 {
+				t.Errorf("expected exponent 0 with a factor, got exponent %d factor %d", exponent, factor)
+			}
+
+			if len(exceptions) != 0 {
+				t.Errorf("expected no exceptions, got %v", exceptions)
+			}
+
+			for i, v := range tt.expected {
+				if scaled[i] != v {
+					t.Errorf("scaled[%d]: expected %d, got %d", i, v, scaled[i])
+				}
+			}
+
+			decoded := ALPDecode(scaled, exponent, factor)
+			for i := range tt.input {
+				if decoded[i] != tt.input[i] {
+					t.Errorf("round-trip[%d]: expected %v, got %v", i, tt.input[i], decoded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestALPDecode_Factor(t *testing.T) {
+	result := ALPDecode([]int64{125, -3}, 0, 10)
+
+	expected := []float64{1.25e12, -3e10}
+	for i, v := range expected {
+		if result[i] != v {
+			t.Errorf("result[%d]: expected %v, got %v", i, v, result[i])
+		}
+	}
+}
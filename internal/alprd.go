@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// ALP-RD payload format (ModeFloatRD):
+// Offset  Size  Field
+// 0       1B    Right part bit width
+// 1       1B    Dictionary size (1-8)
+// 2       2B*n  Dictionary of left parts (uint16, big-endian)
+// ...           Left exceptions: uvarint count, then (uvarint position gap, 2B left part)
+// ...           Left dictionary codes, bit-packed
+// ...           Right parts, bit-packed
+
+const (
+	// Left parts are at most 16 bits wide, as in the ALP paper
+	alprdMinRightWidth = 48
+	alprdMaxDictSize   = 8
+)
+
+// ALPRDEncode splits the raw bits of each value into a left part, encoded
+// through a small dictionary, and a right part, bit-packed as is. It is meant
+// for doubles without decimal structure and is lossless for every bit pattern.
+func ALPRDEncode(input []float64) []byte {
+	if len(input) == 0 {
+		return nil
+	}
+
+	rightWidth, dict := alprdChooseLayout(sampleValues(input, precisionSampleSize))
+
+	codes := make(map[uint64]uint64, len(dict))
+	for i, left := range dict {
+		codes[left] = uint64(i)
+	}
+
+	buf := []byte{byte(rightWidth), byte(len(dict))}
+	for _, left := range dict {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(left))
+	}
+
+	var exceptions []Exception
+	var w bitWriter
+	codeWidth := bits.Len(uint(len(dict) - 1))
+	for i, val := range input {
+		left := math.Float64bits(val) >> rightWidth
+		code, ok := codes[left]
+		if !ok {
+			exceptions = append(exceptions, Exception{Position: i, Bits: left})
+		}
+		w.writeBits(code, codeWidth)
+	}
+	for _, val := range input {
+		w.writeBits(math.Float64bits(val), rightWidth)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(exceptions)))
+	prev := 0
+	for _, exc := range exceptions {
+		buf = binary.AppendUvarint(buf, uint64(exc.Position-prev))
+		buf = binary.BigEndian.AppendUint16(buf, uint16(exc.Bits))
+		prev = exc.Position
+	}
+
+	return append(buf, w.bytes()...)
+}
+
+// ALPRDDecode reverses ALPRDEncode
+func ALPRDDecode(data []byte, valueCount int) ([]float64, error) {
+	if len(data) < 2 {
+		return nil, errors.New("alp-rd: data too short")
+	}
+
+	rightWidth := int(data[0])
+	dictSize := int(data[1])
+	if rightWidth < alprdMinRightWidth || rightWidth > 63 {
+		return nil, fmt.Errorf("alp-rd: invalid right width %d", rightWidth)
+	}
+	if dictSize < 1 || dictSize > alprdMaxDictSize {
+		return nil, fmt.Errorf("alp-rd: invalid dictionary size %d", dictSize)
+	}
+
+	offset := 2
+	if len(data) < offset+2*dictSize {
+		return nil, errors.New("alp-rd: truncated dictionary")
+	}
+	dict := make([]uint64, dictSize)
+	for i := range dict {
+		dict[i] = uint64(binary.BigEndian.Uint16(data[offset:]))
+		offset += 2
+	}
+
+	count, n := binary.Uvarint(data[offset:])
+	// Every exception takes at least three bytes
+	if n <= 0 || count > uint64(valueCount) || count > uint64(len(data)-offset-n)/3 {
+		return nil, errors.New("alp-rd: invalid exception count")
+	}
+	offset += n
+	exceptions := make([]Exception, 0, count)
+	pos := uint64(0)
+	for i := range count {
+		gap, n := binary.Uvarint(data[offset:])
+		if n <= 0 {
+			return nil, errors.New("alp-rd: invalid exception position")
+		}
+		offset += n
+		if (i > 0 && gap == 0) || gap >= uint64(valueCount)-pos {
+			return nil, fmt.Errorf("alp-rd: invalid exception position gap %d after %d", gap, pos)
+		}
+		pos += gap
+		if len(data)-offset < 2 {
+			return nil, errors.New("alp-rd: truncated exceptions")
+		}
+		exceptions = append(exceptions, Exception{Position: int(pos), Bits: uint64(binary.BigEndian.Uint16(data[offset:]))})
+		offset += 2
+	}
+
+	if uint64(valueCount) > 8*uint64(len(data)-offset)/uint64(rightWidth) {
+		return nil, fmt.Errorf("alp-rd: %d bytes cannot hold %d values", len(data)-offset, valueCount)
+	}
+
+	r := bitReader{data: data[offset:]}
+	codeWidth := bits.Len(uint(dictSize - 1))
+	lefts := make([]uint64, valueCount)
+	for i := range lefts {
+		code, err := r.readBits(codeWidth)
+		if err != nil {
+			return nil, fmt.Errorf("alp-rd: %w", err)
+		}
+		if code >= uint64(dictSize) {
+			return nil, fmt.Errorf("alp-rd: invalid dictionary code %d", code)
+		}
+		lefts[i] = dict[code]
+	}
+	for _, exc := range exceptions {
+		lefts[exc.Position] = exc.Bits
+	}
+
+	result := make([]float64, valueCount)
+	for i := range result {
+		right, err := r.readBits(rightWidth)
+		if err != nil {
+			return nil, fmt.Errorf("alp-rd: %w", err)
+		}
+		result[i] = math.Float64frombits(lefts[i]<<rightWidth | right)
+	}
+
+	return result, nil
+}
+
+// alprdChooseLayout picks the right part width and left dictionary that
+// minimize the estimated encoded size of sample.
+func alprdChooseLayout(sample []float64) (int, []uint64) {
+	// Left parts of every candidate width are prefixes of the widest one, so
+	// sorting those once lets each width count its values in a single pass
+	widest := make([]uint64, len(sample))
+	for i, val := range sample {
+		widest[i] = math.Float64bits(val) >> alprdMinRightWidth
+	}
+	slices.Sort(widest)
+
+	type run struct {
+		left  uint64
+		count int
+	}
+	runs := make([]run, 0, len(widest))
+
+	bestWidth, bestCost := 0, math.MaxInt
+	var bestDict []uint64
+
+	for rightWidth := alprdMinRightWidth; rightWidth < 64; rightWidth++ {
+		shift := rightWidth - alprdMinRightWidth
+		runs = runs[:0]
+		for _, w := range widest {
+			left := w >> shift
+			if len(runs) > 0 && runs[len(runs)-1].left == left {
+				runs[len(runs)-1].count++
+			} else {
+				runs = append(runs, run{left: left, count: 1})
+			}
+		}
+		slices.SortStableFunc(runs, func(a, b run) int {
+			return b.count - a.count
+		})
+
+		dictSize := min(len(runs), alprdMaxDictSize)
+		covered := 0
+		for _, r := range runs[:dictSize] {
+			covered += r.count
+		}
+		exceptions := len(sample) - covered
+
+		// Exceptions cost a 2-byte left part plus roughly a byte of position
+		codeWidth := bits.Len(uint(dictSize - 1))
+		cost := len(sample)*(codeWidth+rightWidth) + exceptions*24 + dictSize*16
+		if cost < bestCost {
+			bestWidth, bestCost = rightWidth, cost
+			bestDict = bestDict[:0]
+			for _, r := range runs[:dictSize] {
+				bestDict = append(bestDict, r.left)
+			}
+		}
+	}
+
+	return bestWidth, bestDict
+}
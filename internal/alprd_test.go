@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestALPRD_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []float64
+	}{
+		{"single", []float64{math.Pi}},
+		{"divisions", []float64{1.0 / 3, 2.0 / 3, 1.0 / 7, 22.0 / 7, 5.0 / 9}},
+		{"mixed magnitudes", []float64{1e-20, 1e300, -1e-300, 5e-324, math.MaxFloat64, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := ALPRDEncode(tt.input)
+
+			decoded, err := ALPRDDecode(data, len(tt.input))
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+
+			for i := range tt.input {
+				if math.Float64bits(decoded[i]) != math.Float64bits(tt.input[i]) {
+					t.Errorf("round-trip[%d]: expected %v, got %v", i, tt.input[i], decoded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestALPRD_Compresses(t *testing.T) {
+	input := make([]float64, 2000)
+	for i := range input {
+		input[i] = math.Sin(float64(i)*0.001) + 2
+	}
+	// A handful of values outside the dictionary become left exceptions
+	input[10] = -1e10
+	input[500] = 1e-10
+
+	data := ALPRDEncode(input)
+	if len(data) >= 8*len(input) {
+		t.Errorf("expected fewer than %d bytes, got %d", 8*len(input), len(data))
+	}
+
+	decoded, err := ALPRDDecode(data, len(input))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range input {
+		if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+			t.Fatalf("round-trip[%d]: expected %v, got %v", i, input[i], decoded[i])
+		}
+	}
+}
+
+func TestALPRDDecode_Invalid(t *testing.T) {
+	valid := ALPRDEncode([]float64{1.0 / 3, 2.0 / 3, 1.0 / 7})
+	// Two left exceptions, the second gap wrapping the position back to 0
+	wrapping := []byte{48, 1, 0, 0, 2, 1, 0, 0}
+	wrapping = binary.AppendUvarint(wrapping, math.MaxUint64)
+	wrapping = append(wrapping, make([]byte, 32)...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"bad right width", []byte{10, 1, 0, 0, 0}},
+		{"bad dictionary size", []byte{50, 0, 0}},
+		{"truncated", valid[:len(valid)-1]},
+		{"wrapping exception gap", wrapping},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ALPRDDecode(tt.data, 3); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
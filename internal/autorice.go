@@ -6,6 +6,8 @@ import (
 
 // AutoRiceParam calculates the optimal Rice parameter for given data.
 // Uses the median of absolute values as a heuristic, rounded to nearest power of 2.
+// The unrounded median is used instead when its truncated-binary Golomb code is
+// strictly smaller for these deltas.
 // This provides good compression without requiring manual tuning.
 func AutoRiceParam(deltas []int64) int {
 	if len(deltas) == 0 {
@@ -25,8 +27,20 @@ func AutoRiceParam(deltas []int64) int {
 	// Find median
 	median := findMedian(absValues)
 
-	// Round to nearest power of 2 for efficiency and clamp to [1, 64]
-	riceParam := min(max(roundToPowerOf2(median), 1), 64)
+	// Round to nearest power of 2 for efficiency and clamp to what the header
+	// can store: wide residuals need a wide m, or every one would be escaped
+	median = min(median, MaxRiceParam)
+	riceParam := min(roundToPowerOf2(median), MaxRiceParam)
+
+	// A non-power-of-two m sits closer to the median; keep it only if the
+	// encoded output actually shrinks
+	golombParam := int(max(median, 1))
+	if golombParam != riceParam {
+		zigzagged, _ := ZigZagEncode(deltas)
+		if GolombRiceSize(zigzagged, golombParam) < GolombRiceSize(zigzagged, riceParam) {
+			return golombParam
+		}
+	}
 
 	return riceParam
 }
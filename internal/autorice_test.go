@@ -20,9 +20,10 @@ func TestAutoRiceParam_LargeValues(t *testing.T) {
 	deltas := []int64{100, 200, 150, 180, 120}
 	param := AutoRiceParam(deltas)
 
-	// Median is 150, rounded to power of 2 = 128, clamped to 64
-	if param != 64 {
-		t.Errorf("expected rice param 64 for large values, got %d", param)
+	// Median is 150, rounded to power of 2 = 128; Golomb coding with m=150
+	// takes fewer bits, so the median itself wins
+	if param != 150 {
+		t.Errorf("expected rice param 150 for large values, got %d", param)
 	}
 }
 
@@ -34,8 +35,9 @@ func TestAutoRiceParam_MixedSigns(t *testing.T) {
 	// Absolute values: 5, 5, 10, 10, 3, 3
 	// Sorted: 3, 3, 5, 5, 10, 10
 	// Median = (5+5)/2 = 5, rounded to power of 2 = 4
-	if param != 4 {
-		t.Errorf("expected rice param 4 for mixed signs, got %d", param)
+	// Golomb coding with m=5 takes 40 bits against 41 for m=4, so 5 wins
+	if param != 5 {
+		t.Errorf("expected rice param 5 for mixed signs, got %d", param)
 	}
 }
 
@@ -126,3 +128,26 @@ func TestFindMedian(t *testing.T) {
 		}
 	}
 }
+
+func TestAutoRiceParam_PrefersPowerOf2OnTie(t *testing.T) {
+	// Median 42 rounds to 32; m=42 codes these deltas in the same number of
+	// bits, so the power of two is kept
+	param := AutoRiceParam([]int64{42})
+	if param != 32 {
+		t.Errorf("expected rice param 32, got %d", param)
+	}
+}
+
+func TestAutoRiceParam_NonPowerOf2(t *testing.T) {
+	deltas := []int64{11, -11, 12, -12, 10}
+	param := AutoRiceParam(deltas)
+
+	zigzagged, _ := ZigZagEncode(deltas)
+	nearest := roundToPowerOf2(11)
+	if param == nearest {
+		t.Fatalf("expected a non-power-of-two parameter, got %d", param)
+	}
+	if GolombRiceSize(zigzagged, param) >= GolombRiceSize(zigzagged, nearest) {
+		t.Errorf("m=%d is not smaller than m=%d", param, nearest)
+	}
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// bitWriter appends bits MSB-first, the same bit order as the Golomb-Rice stream
+type bitWriter struct {
+	data  []byte
+	nbits int
+}
+
+// writeBits writes the low n bits of value, most significant first. n must be
+// in [0, 64].
+func (w *bitWriter) writeBits(value uint64, n int) {
+	for n > 0 {
+		bitOff := w.nbits % 8
+		if bitOff == 0 {
+			w.data = append(w.data, 0)
+		}
+		free := 8 - bitOff
+		take := min(free, n)
+		chunk := (value >> (n - take)) & (1<<take - 1)
+		w.data[len(w.data)-1] |= byte(chunk << (free - take))
+		n -= take
+		w.nbits += take
+	}
+}
+
+func (w *bitWriter) writeBit(bit uint64) {
+	w.writeBits(bit, 1)
+}
+
+// bytes returns the written data, with the final partial byte zero-padded
+func (w *bitWriter) bytes() []byte {
+	return w.data
+}
+
+// bitReader reads bits MSB-first from a byte slice
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// readBits reads n bits, most significant first. n must be in [0, 64].
+func (r *bitReader) readBits(n int) (uint64, error) {
+	if r.pos+n > len(r.data)*8 {
+		return 0, errUnexpectedEnd
+	}
+	var result uint64
+	for n > 0 {
+		bitOff := r.pos % 8
+		avail := 8 - bitOff
+		take := min(avail, n)
+		chunk := uint64(r.data[r.pos/8]>>(avail-take)) & (1<<take - 1)
+		result = result<<take | chunk
+		r.pos += take
+		n -= take
+	}
+	return result, nil
+}
+
+func (r *bitReader) readBit() (uint64, error) {
+	return r.readBits(1)
+}
+
+// consumed returns the number of bytes touched so far, counting a partially
+// read byte as consumed
+func (r *bitReader) consumed() int {
+	return (r.pos + 7) / 8
+}
+
+// checkPacked reports whether size bytes hold count values bit-packed at width
+// bits. Width 0 takes no bytes at all, so the count is bounded by
+// MaxSegmentValues instead.
+func checkPacked(size, count, width int) error {
+	if width == 0 {
+		if count > MaxSegmentValues {
+			return fmt.Errorf("%d values exceed the segment limit %d", count, MaxSegmentValues)
+		}
+		return nil
+	}
+	if uint64(count) > 8*uint64(size)/uint64(width) {
+		return fmt.Errorf("%d bytes for %d values of %d bits", size, count, width)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestBitIO_RoundTrip(t *testing.T) {
+	values := []struct {
+		value uint64
+		bits  int
+	}{
+		{1, 1},
+		{0, 3},
+		{5, 3},
+		{0xABCD, 16},
+		{0, 0},
+		{0x7F, 7},
+		{0xFFFFFFFFFFFFFFFF, 64},
+		{0x123456789, 37},
+	}
+
+	var w bitWriter
+	total := 0
+	for _, v := range values {
+		w.writeBits(v.value, v.bits)
+		total += v.bits
+	}
+
+	data := w.bytes()
+	if len(data) != (total+7)/8 {
+		t.Fatalf("expected %d bytes, got %d", (total+7)/8, len(data))
+	}
+
+	r := bitReader{data: data}
+	for i, v := range values {
+		got, err := r.readBits(v.bits)
+		if err != nil {
+			t.Fatalf("value %d: read error: %v", i, err)
+		}
+		if got != v.value {
+			t.Errorf("value %d: expected %#x, got %#x", i, v.value, got)
+		}
+	}
+
+	if r.consumed() != len(data) {
+		t.Errorf("consumed: expected %d bytes, got %d", len(data), r.consumed())
+	}
+}
+
+func TestBitIO_MatchesGolombBitOrder(t *testing.T) {
+	var w bitWriter
+	w.writeBit(1)
+	w.writeBits(0, 6)
+	w.writeBit(1)
+
+	data := w.bytes()
+	if len(data) != 1 || data[0] != 0x81 {
+		t.Errorf("expected [0x81], got %x", data)
+	}
+}
+
+func TestBitReader_UnexpectedEnd(t *testing.T) {
+	r := bitReader{data: []byte{0xFF}}
+	if _, err := r.readBits(9); err == nil {
+		t.Error("expected error reading past end of data, got nil")
+	}
+}
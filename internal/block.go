@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Block section format (payload of ModeBlocked):
+// uvarint  block count
+// Block index, one entry per block in series order:
+//   1B       Mode
+//   uvarint  Rice parameter
+//   1B       ALP exponent
+//   1B       ALP factor
+//   varint   First value
+//   varint   Second value
+//   uvarint  Value count
+//   uvarint  Payload length in bytes
+// Block payloads, concatenated in series order. Each is laid out exactly as
+// for a single-segment blob of its mode.
+//
+// The index lets a reader locate the block holding any value and decode it
+// from its own seed values without touching the other payloads.
+
+// Block locates one block of a block section
+type Block struct {
+	Header  *Header
+	Start   int    // index of the block's first value in the series
+	Payload []byte // slice of the section holding the block's payload
+}
+
+// MarshalBlocks builds a block section from per-block headers and payloads.
+func MarshalBlocks(headers []*Header, payloads [][]byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(headers)))
+	for i, h := range headers {
+		buf = appendBlockEntry(buf, h, len(payloads[i]))
+	}
+	for _, payload := range payloads {
+		buf = append(buf, payload...)
+	}
+	return buf
+}
+
+// UnmarshalBlocks parses the index of a block section. Each block header is
+// validated, the payloads must fit in data and hold their value counts, and
+// the value counts must add up to valueCount.
+func UnmarshalBlocks(data []byte, valueCount int) ([]Block, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, errors.New("block section: invalid block count")
+	}
+	// The encoders only split series longer than a block, so a section holds
+	// at least one block; every block holds at least two values and takes a
+	// few bytes of index
+	if count == 0 || count > uint64(valueCount/2) || count > uint64(len(data)) {
+		return nil, fmt.Errorf("block section: %d blocks for %d values", count, valueCount)
+	}
+
+	offset := n
+	blocks := make([]Block, 0, count)
+	sizes := make([]int, 0, count)
+	total := 0
+	for i := range count {
+		h, size, n, err := unmarshalBlockEntry(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		if h.Mode == ModeBlocked {
+			return nil, fmt.Errorf("block %d: nested block section", i)
+		}
+		if err := h.Validate(); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		if h.ValueCount > valueCount-total {
+			return nil, fmt.Errorf("block %d: value count exceeds series length", i)
+		}
+		blocks = append(blocks, Block{Header: h, Start: total})
+		sizes = append(sizes, size)
+		total += h.ValueCount
+		offset += n
+	}
+
+	if total != valueCount {
+		return nil, fmt.Errorf("block section: blocks hold %d values, header says %d", total, valueCount)
+	}
+
+	for i, size := range sizes {
+		if size > len(data)-offset {
+			return nil, fmt.Errorf("block %d: payload of %d bytes exceeds remaining %d", i, size, len(data)-offset)
+		}
+		blocks[i].Payload = data[offset : offset+size]
+		if err := blocks[i].Header.CheckPayload(blocks[i].Payload); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		offset += size
+	}
+
+	return blocks, nil
+}
+
+// FindBlock returns the position in blocks of the block holding value i.
+func FindBlock(blocks []Block, i int) int {
+	return sort.Search(len(blocks), func(b int) bool {
+		return blocks[b].Start+blocks[b].Header.ValueCount > i
+	})
+}
+
+// appendBlockEntry appends the index entry of one block to buf.
+func appendBlockEntry(buf []byte, h *Header, size int) []byte {
+	buf = append(buf, h.Mode.Byte())
+	buf = binary.AppendUvarint(buf, uint64(h.RiceParam))
+	buf = append(buf, byte(h.ALPExp), byte(h.ALPFactor))
+	buf = binary.AppendVarint(buf, h.First)
+	buf = binary.AppendVarint(buf, h.Second)
+	buf = binary.AppendUvarint(buf, uint64(h.ValueCount))
+	return binary.AppendUvarint(buf, uint64(size))
+}
+
+// unmarshalBlockEntry parses the index entry at the start of data and returns
+// the block header, the payload length and the number of bytes consumed.
+func unmarshalBlockEntry(data []byte) (*Header, int, int, error) {
+	if len(data) < 1 {
+		return nil, 0, 0, errors.New("unexpected end of data")
+	}
+
+	h := &Header{Mode: ModeFromByte(data[0])}
+	offset := 1
+
+	riceParam, n := binary.Uvarint(data[offset:])
+	if n <= 0 || riceParam > MaxRiceParam {
+		return nil, 0, 0, errors.New("invalid rice parameter")
+	}
+	h.RiceParam = int(riceParam)
+	offset += n
+
+	if len(data)-offset < 2 {
+		return nil, 0, 0, errors.New("unexpected end of data")
+	}
+	h.ALPExp = int(data[offset])
+	h.ALPFactor = int(data[offset+1])
+	offset += 2
+
+	if h.First, n = binary.Varint(data[offset:]); n <= 0 {
+		return nil, 0, 0, errors.New("invalid first value")
+	}
+	offset += n
+
+	if h.Second, n = binary.Varint(data[offset:]); n <= 0 {
+		return nil, 0, 0, errors.New("invalid second value")
+	}
+	offset += n
+
+	count, n := binary.Uvarint(data[offset:])
+	if n <= 0 || count > uint64(maxInt) {
+		return nil, 0, 0, errors.New("invalid value count")
+	}
+	h.ValueCount = int(count)
+	offset += n
+
+	size, n := binary.Uvarint(data[offset:])
+	if n <= 0 || size > uint64(maxInt) {
+		return nil, 0, 0, errors.New("invalid payload length")
+	}
+	offset += n
+
+	return h, int(size), offset, nil
+}
+
+// maxInt is the largest value of int on this platform
+const maxInt = int(^uint(0) >> 1)
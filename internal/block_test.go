@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestBlocks_RoundTrip(t *testing.T) {
+	headers := []*Header{
+		{Mode: ModeFloat, RiceParam: 12, ALPExp: 2, ALPFactor: 1, First: -4200, Second: 1 << 40, ValueCount: 3},
+		{Mode: ModeFloatXOR, First: -1, ValueCount: 5},
+	}
+	payloads := [][]byte{{7, 6}, {8, 9}}
+
+	data := MarshalBlocks(headers, payloads)
+	blocks, err := UnmarshalBlocks(data, 8)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if len(blocks) != len(headers) {
+		t.Fatalf("expected %d blocks, got %d", len(headers), len(blocks))
+	}
+
+	starts := []int{0, 3}
+	for i, b := range blocks {
+		if *b.Header != *headers[i] {
+			t.Errorf("block %d header: expected %+v, got %+v", i, headers[i], b.Header)
+		}
+		if b.Start != starts[i] {
+			t.Errorf("block %d start: expected %d, got %d", i, starts[i], b.Start)
+		}
+		if string(b.Payload) != string(payloads[i]) {
+			t.Errorf("block %d payload: expected %v, got %v", i, payloads[i], b.Payload)
+		}
+	}
+}
+
+func TestBlocks_TruncatedPayload(t *testing.T) {
+	data := MarshalBlocks([]*Header{{Mode: ModeInt, RiceParam: 4, ValueCount: 10}}, [][]byte{{1, 2, 3}})
+
+	if _, err := UnmarshalBlocks(data[:len(data)-1], 10); err == nil {
+		t.Error("expected error for truncated payload, got nil")
+	}
+}
+
+func TestBlocks_CountMismatch(t *testing.T) {
+	data := MarshalBlocks([]*Header{{Mode: ModeInt, RiceParam: 4, ValueCount: 3}}, [][]byte{nil})
+
+	if _, err := UnmarshalBlocks(data, 4); err == nil {
+		t.Error("expected error for mismatched value count, got nil")
+	}
+}
+
+func TestBlocks_RejectsEmptySection(t *testing.T) {
+	data := MarshalBlocks(nil, nil)
+
+	if _, err := UnmarshalBlocks(data, 0); err == nil {
+		t.Error("expected error for a section without blocks, got nil")
+	}
+}
+
+func TestBlocks_RejectsNested(t *testing.T) {
+	data := MarshalBlocks([]*Header{{Mode: ModeBlocked, ValueCount: 4}}, [][]byte{nil})
+
+	if _, err := UnmarshalBlocks(data, 4); err == nil {
+		t.Error("expected error for nested block section, got nil")
+	}
+}
+
+func TestFindBlock(t *testing.T) {
+	blocks := []Block{
+		{Header: &Header{ValueCount: 4}, Start: 0},
+		{Header: &Header{ValueCount: 4}, Start: 4},
+		{Header: &Header{ValueCount: 5}, Start: 8},
+	}
+
+	tests := []struct {
+		index    int
+		expected int
+	}{
+		{0, 0}, {3, 0}, {4, 1}, {7, 1}, {8, 2}, {12, 2},
+	}
+
+	for _, tt := range tests {
+		if b := FindBlock(blocks, tt.index); b != tt.expected {
+			t.Errorf("value %d: expected block %d, got %d", tt.index, tt.expected, b)
+		}
+	}
+}
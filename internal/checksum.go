@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// ChecksumSize is the length of the checksum trailer
+const ChecksumSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// AppendChecksum appends the CRC32C of blob (big-endian) to blob.
+func AppendChecksum(blob []byte) []byte {
+	return binary.BigEndian.AppendUint32(blob, crc32.Checksum(blob, castagnoli))
+}
+
+// VerifyChecksum checks the trailer written by AppendChecksum and returns blob
+// without it.
+func VerifyChecksum(blob []byte) ([]byte, error) {
+	if len(blob) < ChecksumSize {
+		return nil, errors.New("checksum: unexpected end of data")
+	}
+
+	body := blob[:len(blob)-ChecksumSize]
+	stored := binary.BigEndian.Uint32(blob[len(body):])
+	if computed := crc32.Checksum(body, castagnoli); computed != stored {
+		return nil, fmt.Errorf("checksum mismatch: stored %#08x, computed %#08x", stored, computed)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestChecksum_RoundTrip(t *testing.T) {
+	blob := []byte("alpine payload")
+
+	sealed := AppendChecksum(append([]byte(nil), blob...))
+	if len(sealed) != len(blob)+ChecksumSize {
+		t.Fatalf("expected %d bytes, got %d", len(blob)+ChecksumSize, len(sealed))
+	}
+
+	body, err := VerifyChecksum(sealed)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+
+	if string(body) != string(blob) {
+		t.Errorf("expected body %q, got %q", blob, body)
+	}
+}
+
+func TestChecksum_DetectsBitFlips(t *testing.T) {
+	sealed := AppendChecksum([]byte("alpine payload"))
+
+	for i := range sealed {
+		corrupt := append([]byte(nil), sealed...)
+		corrupt[i] ^= 0x04
+		if _, err := VerifyChecksum(corrupt); err == nil {
+			t.Errorf("byte %d: expected checksum mismatch, got nil", i)
+		}
+	}
+}
+
+func TestChecksum_TooShort(t *testing.T) {
+	if _, err := VerifyChecksum([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error for short data, got nil")
+	}
+}
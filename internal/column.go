@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Column section format (payload of ModeTimeSeries, and of ModeFrame after the
+// column names):
+// uvarint  column count
+// Column directory, one entry per column:
+//   ...      Mode, Rice parameter, ALP exponent and factor and seed values, as
+//            in the compact header
+//   uvarint  Payload length in bytes, omitted for the last column, whose
+//            payload runs to the end of the section
+// Column payloads, concatenated in directory order. Each is laid out exactly
+// as for a single-segment or ModeBlocked blob of its mode.
+//
+// Every column holds one value per row, so the value count is stored once in
+// the blob header, and the directory lets a reader decode any column without
+// touching the others.
+
+// Column locates one column of a column section
+type Column struct {
+	Header  *Header
+	Payload []byte // slice of the section holding the column's payload
+}
+
+// MarshalColumns builds a column section from per-column headers and payloads.
+// The value counts of the headers are not stored.
+func MarshalColumns(headers []*Header, payloads [][]byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(headers)))
+	for i, h := range headers {
+		buf = appendCompactFields(buf, h)
+		if i < len(headers)-1 {
+			buf = binary.AppendUvarint(buf, uint64(len(payloads[i])))
+		}
+	}
+	for _, payload := range payloads {
+		buf = append(buf, payload...)
+	}
+	return buf
+}
+
+// UnmarshalColumns parses the directory of a column section. Each column
+// header is given valueCount values and validated, and the payloads must fit
+// in data.
+func UnmarshalColumns(data []byte, valueCount int) ([]Column, error) {
+	count, n := binary.Uvarint(data)
+	// Every directory entry takes at least one byte
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, errors.New("column section: invalid column count")
+	}
+
+	offset := n
+	columns := make([]Column, 0, count)
+	sizes := make([]int, 0, count)
+	for i := range count {
+		h, n, err := unmarshalCompactFields(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+		offset += n
+
+		size := uint64(0)
+		if i < count-1 {
+			size, n = binary.Uvarint(data[offset:])
+			if n <= 0 || size > uint64(maxInt) {
+				return nil, fmt.Errorf("column %d: invalid payload length", i)
+			}
+			offset += n
+		}
+
+		if h.Mode == ModeTimeSeries || h.Mode == ModeFrame {
+			return nil, fmt.Errorf("column %d: nested column section", i)
+		}
+		h.ValueCount = valueCount
+		if err := h.Validate(); err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+		columns = append(columns, Column{Header: h})
+		sizes = append(sizes, int(size))
+	}
+
+	for i, size := range sizes {
+		// The last column takes whatever the others leave
+		if i == len(sizes)-1 {
+			size = len(data) - offset
+		}
+		if size > len(data)-offset {
+			return nil, fmt.Errorf("column %d: payload of %d bytes exceeds remaining %d", i, size, len(data)-offset)
+		}
+		columns[i].Payload = data[offset : offset+size]
+		offset += size
+	}
+
+	return columns, nil
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestColumns_RoundTrip(t *testing.T) {
+	headers := []*Header{
+		{Mode: ModeInt, RiceParam: 1, First: 1700000000, Second: 1700000060, ValueCount: 6},
+		{Mode: ModeFloat, RiceParam: 37, ALPExp: 2, ALPFactor: 1, First: -2135, Second: 2140, ValueCount: 6},
+		{Mode: ModeBlocked, ValueCount: 6},
+	}
+	payloads := [][]byte{{7}, nil, {8, 9, 10}}
+
+	data := MarshalColumns(headers, payloads)
+	columns, err := UnmarshalColumns(data, 6)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if len(columns) != len(headers) {
+		t.Fatalf("expected %d columns, got %d", len(headers), len(columns))
+	}
+	for i, c := range columns {
+		if *c.Header != *headers[i] {
+			t.Errorf("column %d header: expected %+v, got %+v", i, headers[i], c.Header)
+		}
+		if string(c.Payload) != string(payloads[i]) {
+			t.Errorf("column %d payload: expected %v, got %v", i, payloads[i], c.Payload)
+		}
+	}
+}
+
+func TestColumns_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []*Header
+		count   int
+		trim    int
+	}{
+		{"truncated payload", []*Header{{Mode: ModeInt, RiceParam: 4}, {Mode: ModeFloatRD}}, 4, 3},
+		{"nested", []*Header{{Mode: ModeTimeSeries, ValueCount: 4}}, 4, 0},
+		{"invalid header", []*Header{{Mode: ModeInt, ValueCount: 4}}, 4, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads := make([][]byte, len(tt.headers))
+			for i := range payloads {
+				payloads[i] = []byte{1, 2}
+			}
+			data := MarshalColumns(tt.headers, payloads)
+
+			if _, err := UnmarshalColumns(data[:len(data)-tt.trim], tt.count); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestColumns_RejectsHugeCount(t *testing.T) {
+	if _, err := UnmarshalColumns([]byte{0xFF, 0xFF, 0x03}, 0); err == nil {
+		t.Error("expected error for column count exceeding the data, got nil")
+	}
+}
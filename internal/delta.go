@@ -1,10 +1,13 @@
 package internal
 
-import "errors"
-
+// DeltaEncode returns the predictive deltas of input[2:] along with the two
+// seed values. Shorter series have no deltas, and the seeds they lack are zero.
 func DeltaEncode(input []int64) (deltas []int64, first int64, second int64, err error) {
 	if len(input) < 2 {
-		return nil, 0, 0, errors.New("input must have at least 2 elements")
+		if len(input) == 1 {
+			first = input[0]
+		}
+		return nil, first, 0, nil
 	}
 	first = input[0]
 	second = input[1]
@@ -16,6 +19,8 @@ func DeltaEncode(input []int64) (deltas []int64, first int64, second int64, err
 	return deltas, first, second, nil
 }
 
+// DeltaDecode reverses DeltaEncode. It always returns at least the two seeds;
+// callers trim the result to the series length.
 func DeltaDecode(deltas []int64, first int64, second int64) ([]int64, error) {
 	result := make([]int64, len(deltas)+2)
 	result[0] = first
@@ -28,17 +28,25 @@ func TestDelta_RoundTrip(t *testing.T) {
 	}
 }
 
-func TestDeltaEncode_TooFewValues(t *testing.T) {
-	_, _, _, err := DeltaEncode([]int64{1})
-	if err == nil {
-		t.Error("expected error for single value, got nil")
+func TestDeltaEncode_SingleValue(t *testing.T) {
+	deltas, first, second, err := DeltaEncode([]int64{42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(deltas) != 0 || first != 42 || second != 0 {
+		t.Errorf("expected no deltas and seeds 42, 0, got %v, %d, %d", deltas, first, second)
 	}
 }
 
 func TestDeltaEncode_Empty(t *testing.T) {
-	_, _, _, err := DeltaEncode([]int64{})
-	if err == nil {
-		t.Error("expected error for empty input, got nil")
+	deltas, first, second, err := DeltaEncode([]int64{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(deltas) != 0 || first != 0 || second != 0 {
+		t.Errorf("expected no deltas and zero seeds, got %v, %d, %d", deltas, first, second)
 	}
 }
 
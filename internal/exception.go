@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Exception section format (follows the Golomb-Rice payload):
+// uvarint  exception count
+// then for each exception, in position order:
+// uvarint  gap from the previous exception position (first: absolute position)
+// 8B       raw IEEE-754 bits (uint64, big-endian)
+
+// Exception is a value that does not survive the ALP round trip at the chosen
+// exponent. It is stored verbatim and patched back in after decoding.
+type Exception struct {
+	Position int
+	Bits     uint64
+}
+
+// MarshalExceptions serializes exceptions, which must be sorted by position.
+func MarshalExceptions(exceptions []Exception) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(exceptions)))
+	prev := 0
+	for _, exc := range exceptions {
+		buf = binary.AppendUvarint(buf, uint64(exc.Position-prev))
+		buf = binary.BigEndian.AppendUint64(buf, exc.Bits)
+		prev = exc.Position
+	}
+	return buf
+}
+
+// UnmarshalExceptions parses an exception section and returns the exceptions
+// along with the number of bytes consumed. Positions are checked against
+// valueCount so a corrupt section cannot index outside the decoded series.
+func UnmarshalExceptions(data []byte, valueCount int) ([]Exception, int, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, 0, errors.New("exception section: invalid count")
+	}
+	// Every exception takes at least nine bytes
+	if count > uint64(valueCount) || count > uint64(len(data)-n)/9 {
+		return nil, 0, fmt.Errorf("exception section: %d exceptions for %d values", count, valueCount)
+	}
+
+	offset := n
+	exceptions := make([]Exception, 0, count)
+	pos := uint64(0)
+	for i := range count {
+		gap, n := binary.Uvarint(data[offset:])
+		if n <= 0 {
+			return nil, 0, errors.New("exception section: invalid position")
+		}
+		offset += n
+		if (i > 0 && gap == 0) || gap >= uint64(valueCount)-pos {
+			return nil, 0, fmt.Errorf("exception section: invalid position gap %d after %d", gap, pos)
+		}
+		pos += gap
+		if len(data)-offset < 8 {
+			return nil, 0, errors.New("exception section: unexpected end of data")
+		}
+		exceptions = append(exceptions, Exception{
+			Position: int(pos),
+			Bits:     binary.BigEndian.Uint64(data[offset:]),
+		})
+		offset += 8
+	}
+
+	return exceptions, offset, nil
+}
+
+// PatchExceptions writes the verbatim exception values back into decoded.
+func PatchExceptions(decoded []float64, exceptions []Exception) {
+	for _, exc := range exceptions {
+		decoded[exc.Position] = math.Float64frombits(exc.Bits)
+	}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestExceptions_RoundTrip(t *testing.T) {
+	original := []Exception{
+		{Position: 0, Bits: math.Float64bits(1.23456789012345)},
+		{Position: 7, Bits: math.Float64bits(-0.1)},
+		{Position: 300, Bits: math.Float64bits(1e300)},
+	}
+
+	data := MarshalExceptions(original)
+	decoded, n, err := UnmarshalExceptions(data, 301)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if n != len(data) {
+		t.Errorf("consumed: expected %d bytes, got %d", len(data), n)
+	}
+
+	if len(decoded) != len(original) {
+		t.Fatalf("length: expected %d, got %d", len(original), len(decoded))
+	}
+
+	for i := range original {
+		if decoded[i] != original[i] {
+			t.Errorf("exception[%d]: expected %v, got %v", i, original[i], decoded[i])
+		}
+	}
+}
+
+func TestExceptions_Empty(t *testing.T) {
+	data := MarshalExceptions(nil)
+	if len(data) != 1 {
+		t.Errorf("expected 1 byte for an empty section, got %d", len(data))
+	}
+
+	decoded, _, err := UnmarshalExceptions(data, 10)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if len(decoded) != 0 {
+		t.Errorf("expected no exceptions, got %d", len(decoded))
+	}
+}
+
+func TestUnmarshalExceptions_Invalid(t *testing.T) {
+	valid := MarshalExceptions([]Exception{{Position: 5, Bits: 1}})
+	// A second gap that wraps the position back to 0
+	wrapping := []byte{2, 1, 0, 0, 0, 0, 0, 0, 0, 1}
+	wrapping = binary.AppendUvarint(wrapping, math.MaxUint64)
+	wrapping = append(wrapping, 0, 0, 0, 0, 0, 0, 0, 1)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		valueCount int
+	}{
+		{"empty", []byte{}, 10},
+		{"truncated bits", valid[:len(valid)-1], 10},
+		{"position out of range", valid, 5},
+		{"too many exceptions", []byte{0x05}, 2},
+		{"wrapping gap", wrapping, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := UnmarshalExceptions(tt.data, tt.valueCount)
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestPatchExceptions(t *testing.T) {
+	decoded := []float64{1, 2, 3}
+	PatchExceptions(decoded, []Exception{{Position: 1, Bits: math.Float64bits(2.5)}})
+
+	if decoded[1] != 2.5 {
+		t.Errorf("expected patched value 2.5, got %v", decoded[1])
+	}
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// FastLanes payload format (ModeIntFastLanes, ModeFloatFastLanes), coding the
+// zigzagged residuals in place of the Golomb-Rice stream, in chunks of 1024:
+// 1B       Bit width w of the chunk (0-64)
+// ...      16*k words, little-endian uint64, where k = ceil(rows*w/64) and
+//          rows = ceil(values in the chunk/16); k = w for a full chunk
+//
+// Value i of a chunk is row i/16 of lane i%16. Each lane packs its rows
+// LSB-first into consecutive words, and word j of lane l is stored at index
+// j*16+l, so a row sits at the same bit offset of 16 adjacent words. Unpacking
+// a row is one shift and mask applied to all 16: a branch-free loop without
+// dependencies between lanes, which compilers can unroll and vectorize, in
+// place of the bit-by-bit reads of Golomb-Rice.
+
+const (
+	fastLanesChunk = 1024
+	fastLanesLanes = 16 // 64-bit lanes of a 1024-bit register
+	fastLanesRows  = fastLanesChunk / fastLanesLanes
+)
+
+// FastLanesEncode bit-packs input in the interleaved 1024-value layout, each
+// chunk at the width of its largest value
+func FastLanesEncode(input []uint64) []byte {
+	var buf []byte
+	for start := 0; start < len(input); start += fastLanesChunk {
+		chunk := input[start:min(start+fastLanesChunk, len(input))]
+
+		var width int
+		for _, v := range chunk {
+			width = max(width, bits.Len64(v))
+		}
+
+		buf = append(buf, byte(width))
+		for _, word := range fastLanesPack(chunk, width) {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+	}
+	return buf
+}
+
+// FastLanesDecode reverses FastLanesEncode and returns the number of bytes
+// consumed, so that callers can locate sections stored after it
+func FastLanesDecode(data []byte, valueCount int) ([]uint64, int, error) {
+	// Every chunk takes at least its width byte
+	if chunks := (uint64(valueCount) + fastLanesChunk - 1) / fastLanesChunk; uint64(len(data)) < chunks {
+		return nil, 0, fmt.Errorf("fastlanes: %d bytes cannot hold %d chunks", len(data), chunks)
+	}
+
+	result := make([]uint64, valueCount)
+	words := make([]uint64, fastLanesLanes*64)
+	var out [fastLanesChunk]uint64
+
+	offset := 0
+	for start := 0; start < valueCount; start += fastLanesChunk {
+		n := min(fastLanesChunk, valueCount-start)
+
+		if offset >= len(data) {
+			return nil, 0, errors.New("fastlanes: data too short")
+		}
+		width := int(data[offset])
+		offset++
+		if width > 64 {
+			return nil, 0, fmt.Errorf("fastlanes: bit width %d exceeds 64", width)
+		}
+
+		rows := (n + fastLanesLanes - 1) / fastLanesLanes
+		count := fastLanesWordCount(rows, width)
+		if len(data)-offset < 8*count {
+			return nil, 0, fmt.Errorf("fastlanes: chunk at %d: %d bytes for %d words", start, len(data)-offset, count)
+		}
+		for i := range count {
+			words[i] = binary.LittleEndian.Uint64(data[offset+8*i:])
+		}
+		offset += 8 * count
+
+		fastLanesUnpack(words[:count], width, rows, &out)
+		copy(result[start:start+n], out[:n])
+	}
+
+	return result, offset, nil
+}
+
+// FastLanesSize returns the number of bits FastLanesEncode would produce for
+// input, without encoding it
+func FastLanesSize(input []uint64) uint64 {
+	var size uint64
+	for start := 0; start < len(input); start += fastLanesChunk {
+		chunk := input[start:min(start+fastLanesChunk, len(input))]
+
+		var width int
+		for _, v := range chunk {
+			width = max(width, bits.Len64(v))
+		}
+
+		rows := (len(chunk) + fastLanesLanes - 1) / fastLanesLanes
+		size += 8 + 64*uint64(fastLanesWordCount(rows, width))
+	}
+	return size
+}
+
+// fastLanesWordCount returns the number of words holding rows rows of width
+// bits in each lane
+func fastLanesWordCount(rows, width int) int {
+	return fastLanesLanes * ((rows*width + 63) / 64)
+}
+
+// fastLanesPack packs a chunk of at most 1024 values below 2^width
+func fastLanesPack(chunk []uint64, width int) []uint64 {
+	var in [fastLanesChunk]uint64
+	copy(in[:], chunk)
+
+	rows := (len(chunk) + fastLanesLanes - 1) / fastLanesLanes
+	words := make([]uint64, fastLanesWordCount(rows, width))
+	if width == 0 {
+		return words
+	}
+
+	for row := range rows {
+		bit := row * width
+		k, shift := bit/64, bit%64
+		src := in[row*fastLanesLanes : (row+1)*fastLanesLanes]
+		lo := words[k*fastLanesLanes : (k+1)*fastLanesLanes]
+		for lane := range fastLanesLanes {
+			lo[lane] |= src[lane] << shift
+		}
+		// The row straddles two words of each lane
+		if shift+width > 64 {
+			hi := words[(k+1)*fastLanesLanes : (k+2)*fastLanesLanes]
+			for lane := range fastLanesLanes {
+				hi[lane] |= src[lane] >> (64 - shift)
+			}
+		}
+	}
+	return words
+}
+
+// fastLanesUnpack unpacks the first rows rows of a chunk packed at width bits
+// into out
+func fastLanesUnpack(words []uint64, width, rows int, out *[fastLanesChunk]uint64) {
+	if width == 0 {
+		clear(out[:])
+		return
+	}
+
+	// 1<<64 is 0 in Go, so a 64-bit width keeps every bit
+	mask := uint64(1)<<width - 1
+	for row := range rows {
+		bit := row * width
+		k, shift := bit/64, bit%64
+		dst := out[row*fastLanesLanes : (row+1)*fastLanesLanes]
+		lo := words[k*fastLanesLanes : (k+1)*fastLanesLanes]
+		if shift+width <= 64 {
+			for lane := range fastLanesLanes {
+				dst[lane] = lo[lane] >> shift & mask
+			}
+			continue
+		}
+
+		hi := words[(k+1)*fastLanesLanes : (k+2)*fastLanesLanes]
+		for lane := range fastLanesLanes {
+			dst[lane] = (lo[lane]>>shift | hi[lane]<<(64-shift)) & mask
+		}
+	}
+}
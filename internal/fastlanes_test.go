@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestFastLanes_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	random := func(n, width int) []uint64 {
+		input := make([]uint64, n)
+		for i := range input {
+			input[i] = rng.Uint64() >> (64 - width)
+		}
+		return input
+	}
+
+	tests := []struct {
+		name  string
+		input []uint64
+	}{
+		{"empty", nil},
+		{"zeros", make([]uint64, 1022)},
+		{"partial row", []uint64{1, 2, 3}},
+		{"partial chunk", random(1022, 7)},
+		{"full chunk", random(1024, 13)},
+		{"several chunks", random(3000, 21)},
+		{"full width", append(random(100, 64), math.MaxUint64)},
+		{"straddling words", random(777, 63)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := FastLanesEncode(tt.input)
+			if size := FastLanesSize(tt.input); size != 8*uint64(len(data)) {
+				t.Errorf("expected size %d bits, got %d", 8*len(data), size)
+			}
+
+			// Trailing bytes belong to the next section
+			decoded, consumed, err := FastLanesDecode(append(data, 0xAA, 0xBB), len(tt.input))
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if consumed != len(data) {
+				t.Errorf("expected %d bytes consumed, got %d", len(data), consumed)
+			}
+			if !slices.Equal(decoded, tt.input) {
+				t.Error("decoded values differ from input")
+			}
+		})
+	}
+}
+
+func TestFastLanes_Layout(t *testing.T) {
+	// Value i is row i/16 of lane i%16, so with 4-bit values the first word of
+	// lane l holds the values l, l+16, ..., l+240, lowest bits first
+	input := make([]uint64, 1024)
+	for i := range input {
+		input[i] = uint64(i/16) % 16
+	}
+
+	data := FastLanesEncode(input)
+	if width := data[0]; width != 4 {
+		t.Fatalf("expected width 4, got %d", width)
+	}
+	if len(data) != 1+16*4*8 {
+		t.Fatalf("expected %d bytes, got %d", 1+16*4*8, len(data))
+	}
+	// Every lane's first word holds rows 0-15: 0x0123...EF read from the top
+	for lane := range 16 {
+		if word := data[1+8*lane : 1+8*lane+8]; word[0] != 0x10 || word[7] != 0xFE {
+			t.Errorf("lane %d: unexpected first word % x", lane, word)
+		}
+	}
+}
+
+func TestFastLanesDecode_Invalid(t *testing.T) {
+	input := make([]uint64, 100)
+	for i := range input {
+		input[i] = uint64(i)
+	}
+	data := FastLanesEncode(input)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"width too large", append([]byte{65}, data[1:]...)},
+		{"truncated", data[:len(data)-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := FastLanesDecode(tt.data, len(input)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
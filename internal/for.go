@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Frame-of-reference payload format (ModeIntFOR):
+// Offset  Size  Field
+// 0       1B    Bit width w (0-64)
+// 1             Offsets from the reference, w bits each, bit-packed
+//
+// The reference is the minimum of the block and is stored in the header's
+// First field, so every offset is non-negative and w is the width of max-min.
+// Bounded values without a trend (status codes, queue depths, percentages)
+// pack tighter this way than as Rice-coded residuals of a predictor.
+
+// FOREncode returns the reference of input and its frame-of-reference payload
+func FOREncode(input []int64) (int64, []byte) {
+	if len(input) == 0 {
+		return 0, nil
+	}
+
+	reference, width := forLayout(input)
+
+	w := bitWriter{data: []byte{byte(width)}, nbits: 8}
+	for _, v := range input {
+		w.writeBits(uint64(v)-uint64(reference), width)
+	}
+	return reference, w.bytes()
+}
+
+// FORDecode reverses FOREncode
+func FORDecode(data []byte, reference int64, valueCount int) ([]int64, error) {
+	if valueCount == 0 {
+		return []int64{}, nil
+	}
+	if len(data) == 0 {
+		return nil, errors.New("frame-of-reference: data too short")
+	}
+
+	width := int(data[0])
+	if width > 64 {
+		return nil, fmt.Errorf("frame-of-reference: bit width %d exceeds 64", width)
+	}
+	if err := checkPacked(len(data)-1, valueCount, width); err != nil {
+		return nil, fmt.Errorf("frame-of-reference: %w", err)
+	}
+
+	r := bitReader{data: data[1:]}
+	result := make([]int64, valueCount)
+	for i := range result {
+		offset, err := r.readBits(width)
+		if err != nil {
+			return nil, fmt.Errorf("frame-of-reference: %w", err)
+		}
+		result[i] = int64(uint64(reference) + offset)
+	}
+	return result, nil
+}
+
+// FORSize returns the number of bits of the payload FOREncode would produce
+// for input, without encoding it
+func FORSize(input []int64) uint64 {
+	if len(input) == 0 {
+		return 0
+	}
+	_, width := forLayout(input)
+	return 8 + uint64(width)*uint64(len(input))
+}
+
+// forLayout returns the minimum of input and the bit width of its range
+func forLayout(input []int64) (int64, int) {
+	lo, hi := input[0], input[0]
+	for _, v := range input[1:] {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	return lo, bits.Len64(uint64(hi) - uint64(lo))
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFOR_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []int64
+		width int
+	}{
+		{"single", []int64{42}, 0},
+		{"constant", []int64{7, 7, 7, 7}, 0},
+		{"status codes", []int64{200, 200, 404, 200, 500, 301, 200}, 9},
+		{"percentages", []int64{0, 100, 55, 37, 99, 1}, 7},
+		{"negative", []int64{-5, -1, -8, -3}, 3},
+		{"full range", []int64{math.MinInt64, math.MaxInt64, 0}, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference, data := FOREncode(tt.input)
+			if int(data[0]) != tt.width {
+				t.Errorf("expected width %d, got %d", tt.width, data[0])
+			}
+			if size := FORSize(tt.input); size != uint64(8+tt.width*len(tt.input)) {
+				t.Errorf("expected size %d bits, got %d", 8+tt.width*len(tt.input), size)
+			}
+
+			decoded, err := FORDecode(data, reference, len(tt.input))
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if !slices.Equal(decoded, tt.input) {
+				t.Errorf("expected %v, got %v", tt.input, decoded)
+			}
+		})
+	}
+}
+
+func TestFORDecode_Invalid(t *testing.T) {
+	reference, data := FOREncode([]int64{200, 404, 500, 301})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", data[:len(data)-1]},
+		{"width too large", append([]byte{65}, data[1:]...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FORDecode(tt.data, reference, 4); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFORDecode_CorruptCount(t *testing.T) {
+	reference, data := FOREncode([]int64{200, 404, 500, 301})
+	if _, err := FORDecode(data, reference, maxInt); err == nil {
+		t.Error("expected error for a count the payload cannot hold, got nil")
+	}
+
+	// Zero-width payloads hold any number of values, up to the segment limit
+	reference, data = FOREncode([]int64{7, 7, 7})
+	if _, err := FORDecode(data, reference, MaxSegmentValues+1); err == nil {
+		t.Error("expected error for a zero-width count above the segment limit, got nil")
+	}
+}
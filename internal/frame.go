@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Frame section format (payload of ModeFrame):
+// uvarint  column count
+// Column names, in directory order:
+//   uvarint  name length in bytes
+//   ...      name
+// Column section holding the columns in the same order
+//
+// Names are unique within a frame.
+
+// MarshalFrame builds a frame section from column names, headers and payloads.
+func MarshalFrame(names []string, headers []*Header, payloads [][]byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(names)))
+	for _, name := range names {
+		buf = binary.AppendUvarint(buf, uint64(len(name)))
+		buf = append(buf, name...)
+	}
+	return append(buf, MarshalColumns(headers, payloads)...)
+}
+
+// UnmarshalFrame parses the names and column directory of a frame section.
+// The columns are checked as by UnmarshalColumns.
+func UnmarshalFrame(data []byte, valueCount int) ([]string, []Column, error) {
+	count, n := binary.Uvarint(data)
+	// Every name takes at least its length byte
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, nil, errors.New("frame section: invalid column count")
+	}
+	offset := n
+
+	names := make([]string, 0, count)
+	seen := make(map[string]bool, count)
+	for i := range count {
+		length, n := binary.Uvarint(data[offset:])
+		if n <= 0 || length > uint64(len(data)-offset-n) {
+			return nil, nil, fmt.Errorf("column %d: invalid name length", i)
+		}
+		offset += n
+
+		name := string(data[offset : offset+int(length)])
+		if seen[name] {
+			return nil, nil, fmt.Errorf("column %d: duplicate name %q", i, name)
+		}
+		seen[name] = true
+		names = append(names, name)
+		offset += int(length)
+	}
+
+	columns, err := UnmarshalColumns(data[offset:], valueCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(columns) != len(names) {
+		return nil, nil, fmt.Errorf("frame section: %d names for %d columns", len(names), len(columns))
+	}
+
+	return names, columns, nil
+}
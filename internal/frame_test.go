@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFrame_RoundTrip(t *testing.T) {
+	names := []string{"cpu.user", "cpu.system", ""}
+	headers := []*Header{
+		{Mode: ModeFloat, RiceParam: 8, ALPExp: 1, First: 125, Second: 130, ValueCount: 4},
+		{Mode: ModeFloatXOR, First: -1, ValueCount: 4},
+		{Mode: ModeInt, RiceParam: 2, First: 7, Second: 9, ValueCount: 4},
+	}
+	payloads := [][]byte{{1, 2}, {3}, {4, 5, 6}}
+
+	data := MarshalFrame(names, headers, payloads)
+	gotNames, columns, err := UnmarshalFrame(data, 4)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if !slices.Equal(gotNames, names) {
+		t.Errorf("expected names %q, got %q", names, gotNames)
+	}
+	if len(columns) != len(headers) {
+		t.Fatalf("expected %d columns, got %d", len(headers), len(columns))
+	}
+	for i, c := range columns {
+		if *c.Header != *headers[i] {
+			t.Errorf("column %d header: expected %+v, got %+v", i, headers[i], c.Header)
+		}
+		if string(c.Payload) != string(payloads[i]) {
+			t.Errorf("column %d payload: expected %v, got %v", i, payloads[i], c.Payload)
+		}
+	}
+}
+
+func TestFrame_Invalid(t *testing.T) {
+	header := &Header{Mode: ModeFloatXOR, ValueCount: 2}
+	valid := MarshalFrame([]string{"a", "b"}, []*Header{header, header}, [][]byte{{1}, {2}})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"duplicate names", MarshalFrame([]string{"a", "a"}, []*Header{header, header}, [][]byte{{1}, {2}})},
+		{"name past end", []byte{1, 9, 'a'}},
+		{"names without columns", valid[:5]},
+		{"name count mismatch", append([]byte{1, 1, 'a'}, MarshalColumns([]*Header{header, header}, [][]byte{{1}, {2}})...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := UnmarshalFrame(tt.data, 2); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
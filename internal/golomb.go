@@ -6,6 +6,20 @@ import (
 	"math/bits"
 )
 
+// Golomb-Rice stream format:
+// 1B    Escape threshold T
+// then for each value, MSB-first:
+// q < T:   q zeros, a one, then the remainder in truncated binary
+//          (ceil(log2(m)) - 1 bits for the smallest remainders, ceil(log2(m))
+//          bits otherwise; always ceil(log2(m)) bits when m is a power of two)
+// q >= T:  T zeros, then 6 bits of (bit length - 1) and the value itself
+//
+// The escape bounds the unary run, so a single outlier costs at most
+// T + 70 bits instead of one bit per multiple of m.
+
+// DefaultEscapeThreshold is the quotient at which values are escaped
+const DefaultEscapeThreshold = 32
+
 // PackedData holds the encoded byte data along with metadata needed for decoding
 type PackedData struct {
 	Data       []byte
@@ -17,81 +31,152 @@ func GolombRiceEncode(input []uint64, m int) (PackedData, error) {
 	if len(input) == 0 {
 		return PackedData{}, errors.New("input cannot be empty")
 	}
+	return GolombRiceAppend(PackedData{}, input, m)
+}
+
+// GolombRiceAppend encodes input at the end of packed, a stream produced by
+// GolombRiceEncode or GolombRiceDecodePacked with the same m, and returns the
+// extended stream. An empty packed starts a new stream. Like append, it may
+// reuse the memory of packed.Data.
+func GolombRiceAppend(packed PackedData, input []uint64, m int) (PackedData, error) {
 	if m <= 0 {
 		return PackedData{}, errors.New("m must be positive")
 	}
 
-	output := make([]byte, 0)
-	var currentByte byte
-	var bitPos int = 0
-	totalBits := 0
-
-	addBit := func(bit byte) {
-		currentByte |= (bit & 1) << (7 - bitPos)
-		bitPos++
-		totalBits++
+	w := bitWriter{data: packed.Data, nbits: packed.BitCount}
+	if len(w.data) == 0 {
+		w.writeBits(DefaultEscapeThreshold, 8)
+	} else if w.nbits > len(w.data)*8 || w.nbits < 8 {
+		return PackedData{}, errors.New("bit count does not match data")
+	}
+	threshold := uint64(w.data[0])
 
-		if bitPos == 8 {
-			output = append(output, currentByte)
-			currentByte = 0
-			bitPos = 0
-		}
+	// The padding of the last partial byte is about to be overwritten
+	w.data = w.data[:(w.nbits+7)/8]
+	if pad := w.nbits % 8; pad > 0 {
+		w.data[len(w.data)-1] &= 0xFF << (8 - pad)
 	}
 
-	isPow2 := m > 0 && (m&(m-1)) == 0
+	isPow2 := (m & (m - 1)) == 0
+	k := bits.TrailingZeros(uint(m))
+	bitsNeeded, cutoff := truncatedBinaryParams(m)
 
-	for i := range input {
-		var q uint64
-		var r uint64
+	for _, v := range input {
+		var q, r uint64
 
 		// m=2^k speedup with bit shifting
 		if isPow2 {
-			k := bits.TrailingZeros(uint(m))
-			q = input[i] >> k
-			r = input[i] & uint64(m-1)
+			q = v >> k
+			r = v & uint64(m-1)
 		} else {
-			q = input[i] / uint64(m)
-			r = input[i] % uint64(m)
+			q = v / uint64(m)
+			r = v % uint64(m)
 		}
 
-		// Unary code for quotient. q zeros followed by 1
-		for range q {
-			addBit(0)
+		// Escape: threshold zeros, then the value length-prefixed in binary
+		if q >= threshold {
+			writeZeros(&w, threshold)
+			n := bits.Len64(v)
+			w.writeBits(uint64(n-1), 6)
+			w.writeBits(v, n)
+			continue
 		}
-		addBit(1)
-
-		// Append binary representation of remainder
-		bitsNeeded := int(math.Ceil(math.Log2(float64(m))))
-		for j := bitsNeeded - 1; j >= 0; j-- {
-			if (r>>j)&1 == 1 {
-				addBit(1)
-			} else {
-				addBit(0)
-			}
+
+		// Unary code for quotient. q zeros followed by 1
+		writeZeros(&w, q)
+		w.writeBit(1)
+
+		// Append truncated binary representation of remainder
+		if r < cutoff {
+			w.writeBits(r, bitsNeeded-1)
+		} else {
+			w.writeBits(r+cutoff, bitsNeeded)
 		}
 	}
 
-	// Flush final byte
-	if bitPos > 0 {
-		currentByte &= (0xFF << (8 - bitPos))
-		output = append(output, currentByte)
+	return PackedData{
+		Data:       w.bytes(),
+		BitCount:   w.nbits,
+		ValueCount: packed.ValueCount + len(input),
+	}, nil
+}
+
+// writeZeros writes n zero bits
+func writeZeros(w *bitWriter, n uint64) {
+	for ; n >= 64; n -= 64 {
+		w.writeBits(0, 64)
 	}
+	w.writeBits(0, int(n))
+}
 
-	return PackedData{Data: output, BitCount: totalBits, ValueCount: len(input)}, nil
+// GolombRiceSize returns the number of bits GolombRiceEncode would produce for
+// input with parameter m, without encoding it.
+func GolombRiceSize(input []uint64, m int) uint64 {
+	if m <= 0 {
+		return 0
+	}
+
+	bitsNeeded, cutoff := truncatedBinaryParams(m)
+	total := uint64(8)
+	for _, v := range input {
+		q, r := v/uint64(m), v%uint64(m)
+		if q >= DefaultEscapeThreshold {
+			total += DefaultEscapeThreshold + 6 + uint64(bits.Len64(v))
+			continue
+		}
+		total += q + 1 + uint64(bitsNeeded)
+		if r < cutoff {
+			total--
+		}
+	}
+	return total
+}
+
+// truncatedBinaryParams returns the remainder width ceil(log2(m)) and the
+// number of remainders that are written with one bit less. The cutoff is 0
+// when m is a power of two.
+func truncatedBinaryParams(m int) (int, uint64) {
+	bitsNeeded := int(math.Ceil(math.Log2(float64(m))))
+	return bitsNeeded, uint64(1)<<bitsNeeded - uint64(m)
 }
 
 func GolombRiceDecode(data []byte, bitCount int, valueCount int, m int) ([]uint64, error) {
+	result, _, err := GolombRiceDecodeN(data, valueCount, m)
+	return result, err
+}
+
+// GolombRiceDecodeN decodes valueCount values like GolombRiceDecode and also
+// returns the number of bytes consumed, so that callers can locate sections
+// stored after the Golomb-Rice stream.
+func GolombRiceDecodeN(data []byte, valueCount int, m int) ([]uint64, int, error) {
+	result, packed, err := GolombRiceDecodePacked(data, valueCount, m)
+	return result, len(packed.Data), err
+}
+
+// GolombRiceDecodePacked decodes valueCount values like GolombRiceDecode and
+// also returns the stream they were read from, trimmed to its exact bit count,
+// so that more values can be added with GolombRiceAppend.
+func GolombRiceDecodePacked(data []byte, valueCount int, m int) ([]uint64, PackedData, error) {
 	if len(data) == 0 {
-		return nil, errors.New("data cannot be empty")
+		return nil, PackedData{}, errors.New("data cannot be empty")
 	}
 	if m <= 0 {
-		return nil, errors.New("m must be positive")
+		return nil, PackedData{}, errors.New("m must be positive")
 	}
 	if valueCount <= 0 {
-		return nil, errors.New("valueCount must be positive")
+		return nil, PackedData{}, errors.New("valueCount must be positive")
 	}
 
-	byteIdx := 0
+	threshold := uint64(data[0])
+	if threshold == 0 {
+		return nil, PackedData{}, errors.New("escape threshold must be positive")
+	}
+	// Every value takes at least one bit
+	if uint64(valueCount) > 8*uint64(len(data)-1) {
+		return nil, PackedData{}, errors.New("data too short for value count")
+	}
+
+	byteIdx := 1
 	bitIdx := 0
 
 	readBit := func() (byte, error) {
@@ -107,18 +192,30 @@ func GolombRiceDecode(data []byte, bitCount int, valueCount int, m int) ([]uint6
 		return bit, nil
 	}
 
+	readBits := func(n int) (uint64, error) {
+		var v uint64
+		for range n {
+			bit, err := readBit()
+			if err != nil {
+				return 0, err
+			}
+			v = (v << 1) | uint64(bit)
+		}
+		return v, nil
+	}
+
 	isPow2 := m > 0 && (m&(m-1)) == 0
-	bitsNeeded := int(math.Ceil(math.Log2(float64(m))))
+	bitsNeeded, cutoff := truncatedBinaryParams(m)
 
 	result := make([]uint64, 0, valueCount)
 
 	for range valueCount {
-		// Read quotient
+		// Read quotient, up to the escape threshold
 		var q uint64
-		for {
+		for q < threshold {
 			bit, err := readBit()
 			if err != nil {
-				return nil, err
+				return nil, PackedData{}, err
 			}
 			if bit == 1 {
 				break
@@ -126,14 +223,34 @@ func GolombRiceDecode(data []byte, bitCount int, valueCount int, m int) ([]uint6
 			q++
 		}
 
-		// Read remainder
+		if q == threshold {
+			n, err := readBits(6)
+			if err != nil {
+				return nil, PackedData{}, err
+			}
+			value, err := readBits(int(n) + 1)
+			if err != nil {
+				return nil, PackedData{}, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		// Read truncated binary remainder
 		var r uint64
-		for j := 0; j < bitsNeeded; j++ {
-			bit, err := readBit()
+		if bitsNeeded > 0 {
+			var err error
+			r, err = readBits(bitsNeeded - 1)
 			if err != nil {
-				return nil, err
+				return nil, PackedData{}, err
+			}
+			if r >= cutoff {
+				bit, err := readBit()
+				if err != nil {
+					return nil, PackedData{}, err
+				}
+				r = (r<<1 | uint64(bit)) - cutoff
 			}
-			r = (r << 1) | uint64(bit)
 		}
 
 		// Reconstruct value
@@ -148,5 +265,10 @@ func GolombRiceDecode(data []byte, bitCount int, valueCount int, m int) ([]uint6
 		result = append(result, value)
 	}
 
-	return result, nil
+	consumed := byteIdx
+	if bitIdx > 0 {
+		consumed++
+	}
+
+	return result, PackedData{Data: data[:consumed], BitCount: byteIdx*8 + bitIdx, ValueCount: valueCount}, nil
 }
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"bytes"
+	"math"
 	"testing"
 )
 
@@ -102,3 +104,192 @@ func TestGolombRiceEncode_InvalidParam(t *testing.T) {
 		t.Error("expected error for negative m, got nil")
 	}
 }
+
+func TestGolombRiceDecodeN_Consumed(t *testing.T) {
+	input := []uint64{3, 9, 1, 0, 17}
+	m := 4
+
+	packed, err := GolombRiceEncode(input, m)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Trailing bytes after the stream must be left untouched
+	data := append(packed.Data, 0xAA, 0xBB)
+
+	decoded, consumed, err := GolombRiceDecodeN(data, len(input), m)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if consumed != len(packed.Data) {
+		t.Errorf("consumed: expected %d bytes, got %d", len(packed.Data), consumed)
+	}
+
+	for i, v := range input {
+		if decoded[i] != v {
+			t.Errorf("decoded[%d]: expected %d, got %d", i, v, decoded[i])
+		}
+	}
+}
+
+func TestGolombRiceSize(t *testing.T) {
+	input := []uint64{0, 1, 7, 100, 3, 1 << 40}
+
+	for _, m := range []int{1, 3, 4, 8, 12} {
+		packed, err := GolombRiceEncode(input, m)
+		if err != nil {
+			t.Fatalf("m=%d: encode error: %v", m, err)
+		}
+
+		if size := GolombRiceSize(input, m); size != uint64(packed.BitCount) {
+			t.Errorf("m=%d: expected %d bits, got %d", m, packed.BitCount, size)
+		}
+	}
+}
+
+func TestGolombRice_EscapeOutliers(t *testing.T) {
+	input := []uint64{3, 1 << 40, 5, math.MaxUint64, 0, 4 * DefaultEscapeThreshold}
+	m := 4
+
+	packed, err := GolombRiceEncode(input, m)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Without the escape, 2^40 alone would need 2^38 unary bits
+	if len(packed.Data) > 64 {
+		t.Errorf("expected outliers to be escaped, got %d bytes", len(packed.Data))
+	}
+
+	if packed.Data[0] != DefaultEscapeThreshold {
+		t.Errorf("expected escape threshold %d in the stream, got %d", DefaultEscapeThreshold, packed.Data[0])
+	}
+
+	decoded, err := GolombRiceDecode(packed.Data, packed.BitCount, packed.ValueCount, m)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i, v := range input {
+		if decoded[i] != v {
+			t.Errorf("decoded[%d]: expected %d, got %d", i, v, decoded[i])
+		}
+	}
+}
+
+func TestGolombRiceDecode_StreamThreshold(t *testing.T) {
+	// Threshold 2 with m=1: value 1 is "01", value 5 is escaped as
+	// "00" + "000010" (length 3) + "101"
+	data := []byte{2, 0b01000000, 0b10101000}
+
+	decoded, err := GolombRiceDecode(data, 0, 2, 1)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if decoded[0] != 1 || decoded[1] != 5 {
+		t.Errorf("expected [1 5], got %v", decoded)
+	}
+}
+
+func TestGolombRiceDecode_ZeroThreshold(t *testing.T) {
+	if _, err := GolombRiceDecode([]byte{0, 0xFF}, 0, 1, 4); err == nil {
+		t.Error("expected error for zero escape threshold, got nil")
+	}
+}
+
+func TestGolombRice_TruncatedBinary(t *testing.T) {
+	// m=12 needs 4 remainder bits, but remainders 0..3 fit in 3
+	input := []uint64{0, 3, 4, 11, 12, 27, 35, 1}
+	m := 12
+
+	packed, err := GolombRiceEncode(input, m)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Preamble plus unary quotients plus full 4-bit remainders, minus one bit
+	// for each of the five values with remainder below the cutoff of 4
+	expected := 8 + (1 + 1 + 1 + 1 + 2 + 3 + 3 + 1) + 4*len(input) - 5
+	if packed.BitCount != expected {
+		t.Errorf("expected %d bits, got %d", expected, packed.BitCount)
+	}
+
+	decoded, err := GolombRiceDecode(packed.Data, packed.BitCount, packed.ValueCount, m)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i, v := range input {
+		if decoded[i] != v {
+			t.Errorf("decoded[%d]: expected %d, got %d", i, v, decoded[i])
+		}
+	}
+}
+
+func TestGolombRice_NonPowerOf2RoundTrip(t *testing.T) {
+	input := make([]uint64, 500)
+	for i := range input {
+		input[i] = uint64(i*7919) % 97
+	}
+
+	for m := 1; m <= 40; m++ {
+		packed, err := GolombRiceEncode(input, m)
+		if err != nil {
+			t.Fatalf("m=%d: encode error: %v", m, err)
+		}
+
+		decoded, err := GolombRiceDecode(packed.Data, packed.BitCount, packed.ValueCount, m)
+		if err != nil {
+			t.Fatalf("m=%d: decode error: %v", m, err)
+		}
+
+		for i, v := range input {
+			if decoded[i] != v {
+				t.Fatalf("m=%d: decoded[%d]: expected %d, got %d", m, i, v, decoded[i])
+			}
+		}
+	}
+}
+
+func TestGolombRiceAppend(t *testing.T) {
+	input := []uint64{3, 0, 7, 1, 200, 12, 5, 9, 0, 2}
+
+	for _, m := range []int{1, 3, 4, 8} {
+		want, err := GolombRiceEncode(input, m)
+		if err != nil {
+			t.Fatalf("m=%d: encode error: %v", m, err)
+		}
+
+		// Appending piece by piece produces the same stream as one encode
+		var packed PackedData
+		for i := range input {
+			packed, err = GolombRiceAppend(packed, input[i:i+1], m)
+			if err != nil {
+				t.Fatalf("m=%d: append error: %v", m, err)
+			}
+		}
+		if !bytes.Equal(packed.Data, want.Data) || packed.BitCount != want.BitCount || packed.ValueCount != len(input) {
+			t.Errorf("m=%d: appended stream differs from encoded one", m)
+		}
+
+		// A stream recovered by GolombRiceDecodePacked can be extended, even
+		// when followed by other bytes
+		head, err := GolombRiceEncode(input[:4], m)
+		if err != nil {
+			t.Fatalf("m=%d: encode error: %v", m, err)
+		}
+		_, recovered, err := GolombRiceDecodePacked(append(head.Data, 0xFF, 0xFF), 4, m)
+		if err != nil {
+			t.Fatalf("m=%d: decode error: %v", m, err)
+		}
+		extended, err := GolombRiceAppend(recovered, input[4:], m)
+		if err != nil {
+			t.Fatalf("m=%d: append error: %v", m, err)
+		}
+		if !bytes.Equal(extended.Data, want.Data) || extended.BitCount != want.BitCount {
+			t.Errorf("m=%d: extended stream differs from encoded one", m)
+		}
+	}
+}
@@ -4,68 +4,367 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"math/bits"
 )
 
-// Header format:
+// Header format (version 4, full):
 // Offset  Size  Field
-// 0       1B    Mode
-// 1       1B    Rice parameter
-// 2       1B    ALP exponent (or reserved for int modes)
-// 3       1B    Reserved
-// 4       8B    First value (int64, big-endian)
-// 12      8B    Second value (int64, big-endian)
-// 20      4B    Value count (uint32, big-endian)
-// 24      ...   Payload
+// 0       3B    Magic "ALP"
+// 3       1B    Format version
+// 4       1B    Flags (FlagChecksum, FlagNullable)
+// 5       1B    Mode
+// 6       1B    ALP exponent (or reserved for int modes)
+// 7       1B    ALP factor (or reserved for int modes)
+// 8       4B    Rice parameter (uint32, big-endian; 0 for modes without a Golomb-Rice payload)
+// 12      8B    First value (int64, big-endian; raw float bits for ModeFloatXOR)
+// 20      8B    Second value (int64, big-endian)
+// 28      8B    Value count (uint64, big-endian)
+// 36      ...   Payload
+// With FlagChecksum, a 4-byte checksum trailer follows the payload.
+//
+// Compact header (version 4), used when it is smaller than the full header:
+// 3B       Magic "ALP"
+// 1B       Format version with compactBit set
+// 1B       Flags
+// 1B       Mode (high nibble) and Rice code (low nibble): 0 for no Rice
+//          parameter, 1-14 for 2^(code-1), 15 when a uvarint parameter follows
+// uvarint  Rice parameter (only for Rice code 15)
+// 1B       ALP exponent (ALP-scaled modes only: ModeFloat, ModeFloatPFOR and
+//          ModeFloatFastLanes)
+// 1B       ALP factor (ALP-scaled modes only)
+// varint   First value
+// varint   Second value
+// uvarint  Value count
+//
+// Older versions, still read by Unmarshal:
+// Version 3: the full layout above, without the compact variant
+// Version 2: flags, mode, rice, exponent, factor, first, second, count, with a
+// 1-byte Rice parameter and a uint32 value count (29 bytes)
+// Version 1: the same as version 2 without the flags byte (28 bytes)
 
-const HeaderSize = 24
+// HeaderSize is the size of the full header
+const HeaderSize = 36
+
+// Magic identifies an alpine blob
+const Magic = "ALP"
+
+// Version is the format version written by Marshal. Unmarshal reads every
+// version up to and including it.
+const Version = 4
+
+// compactBit marks a compact header in the version byte
+const compactBit = 0x80
+
+// Rice codes of the compact header
+const (
+	compactRiceMaxShift = 14 // codes 1-14 stand for 2^0 through 2^13
+	compactRiceVarint   = 15 // a uvarint Rice parameter follows
+)
+
+// MaxRiceParam is the largest Golomb-Rice parameter the header can store
+const MaxRiceParam = math.MaxInt32
+
+// MaxSegmentValues is the largest number of values of one segment: a
+// single-segment series or one block. Payloads bit-packed at width 0 hold any
+// number of equal values in a byte or two, so their count cannot be checked
+// against the payload; this bounds it, and with it what a corrupt count can
+// make the decoder allocate, instead.
+const MaxSegmentValues = 1 << 20
+
+// Header flags
+const (
+	// FlagChecksum marks a blob that ends in a CRC32C checksum trailer
+	FlagChecksum uint8 = 1 << iota
+
+	// FlagNullable marks a blob whose payload starts with a validity section.
+	// The value count and payload then cover only the present values.
+	FlagNullable
+)
+
+// knownFlags holds every flag this build understands
+const knownFlags = FlagChecksum | FlagNullable
 
 type Header struct {
+	Flags      uint8
 	Mode       Mode
 	RiceParam  int
 	ALPExp     int
+	ALPFactor  int
 	First      int64
 	Second     int64
 	ValueCount int
 }
 
+// Marshal writes the header in the current format version. Fields must be
+// within the ranges checked by Validate.
 func (h *Header) Marshal() []byte {
 	buf := make([]byte, HeaderSize)
-	buf[0] = h.Mode.Byte()
-	buf[1] = byte(h.RiceParam)
-	buf[2] = byte(h.ALPExp)
-	buf[3] = 0 // Reserved
-	binary.BigEndian.PutUint64(buf[4:12], uint64(h.First))
-	binary.BigEndian.PutUint64(buf[12:20], uint64(h.Second))
-	binary.BigEndian.PutUint32(buf[20:24], uint32(h.ValueCount))
+	copy(buf, Magic)
+	buf[3] = Version
+	buf[4] = h.Flags
+	buf[5] = h.Mode.Byte()
+	buf[6] = byte(h.ALPExp)
+	buf[7] = byte(h.ALPFactor)
+	binary.BigEndian.PutUint32(buf[8:12], uint32(h.RiceParam))
+	binary.BigEndian.PutUint64(buf[12:20], uint64(h.First))
+	binary.BigEndian.PutUint64(buf[20:28], uint64(h.Second))
+	binary.BigEndian.PutUint64(buf[28:36], uint64(h.ValueCount))
 	return buf
 }
 
-func Unmarshal(data []byte) (*Header, error) {
-	if len(data) < HeaderSize {
-		return nil, fmt.Errorf("data too short: need at least %d bytes, got %d", HeaderSize, len(data))
+// MarshalCompact writes the header in the compact layout. It returns nil when
+// the mode does not fit in the mode nibble.
+func (h *Header) MarshalCompact() []byte {
+	if h.Mode > 0xF {
+		return nil
+	}
+
+	buf := append([]byte(Magic), Version|compactBit, h.Flags)
+	buf = appendCompactFields(buf, h)
+	return binary.AppendUvarint(buf, uint64(h.ValueCount))
+}
+
+// appendCompactFields appends the fields of the compact layout between the
+// flags and the value count: mode and Rice code, Rice parameter, ALP exponent
+// and factor, and the seed values. The mode must fit in the mode nibble.
+func appendCompactFields(buf []byte, h *Header) []byte {
+	code := byte(compactRiceVarint)
+	if h.RiceParam == 0 {
+		code = 0
+	} else if shift := bits.TrailingZeros(uint(h.RiceParam)); h.RiceParam == 1<<shift && shift < compactRiceMaxShift {
+		code = byte(shift + 1)
+	}
+	buf = append(buf, h.Mode.Byte()<<4|code)
+	if code == compactRiceVarint {
+		buf = binary.AppendUvarint(buf, uint64(h.RiceParam))
+	}
+
+	if h.Mode.UsesALP() {
+		buf = append(buf, byte(h.ALPExp), byte(h.ALPFactor))
+	}
+
+	buf = binary.AppendVarint(buf, h.First)
+	return binary.AppendVarint(buf, h.Second)
+}
+
+// Unmarshal checks the magic signature and parses the header of any supported
+// format version, full or compact. It returns the header and its size in bytes.
+func Unmarshal(data []byte) (*Header, int, error) {
+	if len(data) < len(Magic)+1 || string(data[:len(Magic)]) != Magic {
+		return nil, 0, errors.New("not an alpine blob: missing magic signature")
+	}
+
+	version := data[len(Magic)]
+	if version&compactBit != 0 {
+		return unmarshalCompact(data)
+	}
+	if version < 1 || version > Version {
+		return nil, 0, fmt.Errorf("unsupported format version %d (this build reads up to %d)", version, Version)
+	}
+
+	sizes := [...]int{1: 28, 2: 29, 3: HeaderSize, 4: HeaderSize}
+	if len(data) < sizes[version] {
+		return nil, 0, fmt.Errorf("data too short: need at least %d bytes, got %d", sizes[version], len(data))
+	}
+
+	switch version {
+	case 1:
+		return unmarshalNarrow(data, 0, 4), sizes[version], nil
+	case 2:
+		return unmarshalNarrow(data, data[4], 5), sizes[version], nil
+	}
+
+	count := binary.BigEndian.Uint64(data[28:36])
+	if count > uint64(maxInt) {
+		return nil, 0, fmt.Errorf("value count %d exceeds this platform's limit", count)
 	}
 
 	h := &Header{
-		Mode:       ModeFromByte(data[0]),
-		RiceParam:  int(data[1]),
-		ALPExp:     int(data[2]),
-		First:      int64(binary.BigEndian.Uint64(data[4:12])),
-		Second:     int64(binary.BigEndian.Uint64(data[12:20])),
-		ValueCount: int(binary.BigEndian.Uint32(data[20:24])),
+		Flags:      data[4],
+		Mode:       ModeFromByte(data[5]),
+		ALPExp:     int(data[6]),
+		ALPFactor:  int(data[7]),
+		RiceParam:  int(binary.BigEndian.Uint32(data[8:12])),
+		First:      int64(binary.BigEndian.Uint64(data[12:20])),
+		Second:     int64(binary.BigEndian.Uint64(data[20:28])),
+		ValueCount: int(count),
 	}
 
-	return h, nil
+	return h, HeaderSize, nil
+}
+
+// unmarshalCompact parses the compact layout written by MarshalCompact
+func unmarshalCompact(data []byte) (*Header, int, error) {
+	version := data[len(Magic)] &^ compactBit
+	if version < 4 || version > Version {
+		return nil, 0, fmt.Errorf("unsupported format version %d (compact header, this build reads up to %d)", version, Version)
+	}
+
+	offset := len(Magic) + 1
+	if len(data)-offset < 1 {
+		return nil, 0, errors.New("compact header: unexpected end of data")
+	}
+	flags := data[offset]
+	offset++
+
+	h, n, err := unmarshalCompactFields(data[offset:])
+	if err != nil {
+		return nil, 0, fmt.Errorf("compact header: %w", err)
+	}
+	h.Flags = flags
+	offset += n
+
+	count, n := binary.Uvarint(data[offset:])
+	if n <= 0 || count > uint64(maxInt) {
+		return nil, 0, errors.New("compact header: invalid value count")
+	}
+	h.ValueCount = int(count)
+	offset += n
+
+	return h, offset, nil
+}
+
+// unmarshalCompactFields parses the fields written by appendCompactFields and
+// returns them with the number of bytes consumed
+func unmarshalCompactFields(data []byte) (*Header, int, error) {
+	if len(data) < 1 {
+		return nil, 0, errors.New("unexpected end of data")
+	}
+
+	h := &Header{Mode: ModeFromByte(data[0] >> 4)}
+	code := data[0] & 0xF
+	offset := 1
+
+	switch {
+	case code == compactRiceVarint:
+		riceParam, n := binary.Uvarint(data[offset:])
+		if n <= 0 || riceParam > MaxRiceParam {
+			return nil, 0, errors.New("invalid rice parameter")
+		}
+		h.RiceParam = int(riceParam)
+		offset += n
+	case code > 0:
+		h.RiceParam = 1 << (code - 1)
+	}
+
+	if h.Mode.UsesALP() {
+		if len(data)-offset < 2 {
+			return nil, 0, errors.New("unexpected end of data")
+		}
+		h.ALPExp = int(data[offset])
+		h.ALPFactor = int(data[offset+1])
+		offset += 2
+	}
+
+	var n int
+	if h.First, n = binary.Varint(data[offset:]); n <= 0 {
+		return nil, 0, errors.New("invalid first value")
+	}
+	offset += n
+
+	if h.Second, n = binary.Varint(data[offset:]); n <= 0 {
+		return nil, 0, errors.New("invalid second value")
+	}
+	offset += n
+
+	return h, offset, nil
+}
+
+// unmarshalNarrow parses the fixed-width fields of versions 1 and 2, starting
+// at offset
+func unmarshalNarrow(data []byte, flags uint8, offset int) *Header {
+	fields := data[offset : offset+24]
+	return &Header{
+		Flags:      flags,
+		Mode:       ModeFromByte(fields[0]),
+		RiceParam:  int(fields[1]),
+		ALPExp:     int(fields[2]),
+		ALPFactor:  int(fields[3]),
+		First:      int64(binary.BigEndian.Uint64(fields[4:12])),
+		Second:     int64(binary.BigEndian.Uint64(fields[12:20])),
+		ValueCount: int(binary.BigEndian.Uint32(fields[20:24])),
+	}
 }
 
 // Validate checks if the header is valid
 func (h *Header) Validate() error {
-	if h.RiceParam <= 0 {
+	if h.Flags&^knownFlags != 0 {
+		return fmt.Errorf("unknown flags %#x", h.Flags&^knownFlags)
+	}
+
+	if h.Mode.UsesRice() && h.RiceParam <= 0 {
 		return errors.New("rice parameter must be positive")
 	}
 
-	if h.ValueCount < 2 {
-		return errors.New("value count must be at least 2")
+	if h.RiceParam > MaxRiceParam {
+		return fmt.Errorf("rice parameter %d exceeds maximum %d", h.RiceParam, MaxRiceParam)
+	}
+
+	if h.Mode.UsesALP() && (h.ALPExp > MaxALPExponent || h.ALPFactor > MaxALPExponent) {
+		return fmt.Errorf("alp exponent and factor must not exceed %d", MaxALPExponent)
+	}
+
+	if h.ValueCount < 0 {
+		return errors.New("value count must not be negative")
 	}
 
 	return nil
 }
+
+// CheckPayload reports whether payload can hold the value count of a
+// single-segment header, given the fewest bits its mode spends per value, so
+// that a corrupt count is rejected before anything is allocated for it
+func (h *Header) CheckPayload(payload []byte) error {
+	switch h.Mode {
+	case ModeBlocked, ModeTimeSeries, ModeFrame:
+		// Their sections check the counts of the segments they hold
+		return nil
+	}
+
+	if h.ValueCount > MaxSegmentValues {
+		return fmt.Errorf("value count %d exceeds the segment limit %d", h.ValueCount, MaxSegmentValues)
+	}
+	if need := minPayloadBits(h.Mode, h.ValueCount); 8*uint64(len(payload)) < need {
+		return fmt.Errorf("%d-byte payload cannot hold %d values", len(payload), h.ValueCount)
+	}
+	return nil
+}
+
+// minPayloadBits returns the size of the smallest payload of mode holding
+// count values
+func minPayloadBits(mode Mode, count int) uint64 {
+	if count == 0 {
+		return 0
+	}
+	n := uint64(count)
+	residuals := uint64(max(count-2, 0))
+
+	switch {
+	case residuals == 0 && (mode.UsesRice() || mode.UsesPFOR()):
+		return 0
+	case mode.UsesRice():
+		// Escape threshold, then at least one bit per residual
+		return 8 + residuals
+	case mode.UsesPFOR():
+		// Exception count and bit width, which may be 0
+		return 16
+	case mode.UsesFastLanes():
+		// A bit width per chunk of residuals
+		return 8 * ((residuals + fastLanesChunk - 1) / fastLanesChunk)
+	}
+
+	switch mode {
+	case ModeIntFOR:
+		// Bit width, which may be 0
+		return 8
+	case ModeFloatXOR:
+		// At least one bit per value after the first
+		return n - 1
+	case ModeFloatRD:
+		// Right width, dictionary size and entry, exception count, then at
+		// least the right part of every value
+		return 8*5 + alprdMinRightWidth*n
+	}
+	return 0
+}
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"encoding/binary"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -20,12 +23,16 @@ func TestHeader_Marshal(t *testing.T) {
 		t.Errorf("expected header size %d, got %d", HeaderSize, len(data))
 	}
 
-	if data[0] != byte(ModeInt) {
-		t.Errorf("expected mode %d, got %d", ModeInt, data[0])
+	if string(data[:3]) != Magic || data[3] != Version {
+		t.Errorf("expected magic %q version %d, got %q version %d", Magic, Version, data[:3], data[3])
 	}
 
-	if data[1] != 8 {
-		t.Errorf("expected rice param 8, got %d", data[1])
+	if data[5] != byte(ModeInt) {
+		t.Errorf("expected mode %d, got %d", ModeInt, data[5])
+	}
+
+	if rice := binary.BigEndian.Uint32(data[8:12]); rice != 8 {
+		t.Errorf("expected rice param 8, got %d", rice)
 	}
 }
 
@@ -34,13 +41,14 @@ func TestHeader_Unmarshal(t *testing.T) {
 		Mode:       ModeFloat,
 		RiceParam:  4,
 		ALPExp:     5,
+		ALPFactor:  3,
 		First:      42,
 		Second:     100,
 		ValueCount: 500,
 	}
 
 	data := original.Marshal()
-	decoded, err := Unmarshal(data)
+	decoded, _, err := Unmarshal(data)
 
 	if err != nil {
 		t.Fatalf("unmarshal failed: %v", err)
@@ -54,6 +62,10 @@ func TestHeader_Unmarshal(t *testing.T) {
 		t.Errorf("rice param: expected %d, got %d", original.RiceParam, decoded.RiceParam)
 	}
 
+	if decoded.ALPFactor != original.ALPFactor {
+		t.Errorf("alp factor: expected %d, got %d", original.ALPFactor, decoded.ALPFactor)
+	}
+
 	if decoded.First != original.First {
 		t.Errorf("first: expected %d, got %d", original.First, decoded.First)
 	}
@@ -89,12 +101,47 @@ func TestHeader_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "xor mode without rice param",
+			header: &Header{
+				Mode:       ModeFloatXOR,
+				RiceParam:  0,
+				ValueCount: 10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "alp factor out of range",
+			header: &Header{
+				Mode:       ModeFloat,
+				RiceParam:  4,
+				ALPFactor:  18,
+				ValueCount: 10,
+			},
+			wantErr: true,
+		},
 		{
 			name: "single value",
 			header: &Header{
 				RiceParam:  4,
 				ValueCount: 1,
 			},
+			wantErr: false,
+		},
+		{
+			name: "empty",
+			header: &Header{
+				RiceParam:  4,
+				ValueCount: 0,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative count",
+			header: &Header{
+				RiceParam:  4,
+				ValueCount: -1,
+			},
 			wantErr: true,
 		},
 	}
@@ -110,13 +157,36 @@ func TestHeader_Validate(t *testing.T) {
 }
 
 func TestHeader_Unmarshal_TooShort(t *testing.T) {
-	data := make([]byte, HeaderSize-1)
-	_, err := Unmarshal(data)
+	data := (&Header{Mode: ModeInt, RiceParam: 4, ValueCount: 2}).Marshal()
+	_, _, err := Unmarshal(data[:HeaderSize-1])
 	if err == nil {
 		t.Error("expected error for short data")
 	}
 }
 
+func TestHeader_Unmarshal_BadMagic(t *testing.T) {
+	data := (&Header{Mode: ModeInt, RiceParam: 4, ValueCount: 2}).Marshal()
+	data[0] = 'X'
+
+	if _, _, err := Unmarshal(data); err == nil {
+		t.Error("expected error for missing magic signature")
+	}
+
+	if _, _, err := Unmarshal(make([]byte, HeaderSize)); err == nil {
+		t.Error("expected error for zeroed data")
+	}
+}
+
+func TestHeader_Unmarshal_UnknownVersion(t *testing.T) {
+	data := (&Header{Mode: ModeInt, RiceParam: 4, ValueCount: 2}).Marshal()
+	data[3] = Version + 1
+
+	_, _, err := Unmarshal(data)
+	if err == nil || !strings.Contains(err.Error(), "unsupported format version") {
+		t.Errorf("expected unsupported version error, got %v", err)
+	}
+}
+
 func TestHeader_RoundTrip(t *testing.T) {
 	tests := []struct {
 		name string
@@ -124,6 +194,7 @@ func TestHeader_RoundTrip(t *testing.T) {
 	}{
 		{"Float", ModeFloat},
 		{"Int", ModeInt},
+		{"FloatXOR", ModeFloatXOR},
 	}
 
 	for _, tt := range tests {
@@ -138,7 +209,7 @@ func TestHeader_RoundTrip(t *testing.T) {
 			}
 
 			data := h.Marshal()
-			decoded, err := Unmarshal(data)
+			decoded, _, err := Unmarshal(data)
 			if err != nil {
 				t.Fatalf("unmarshal failed: %v", err)
 			}
@@ -149,3 +220,209 @@ func TestHeader_RoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestHeader_UnmarshalVersion1(t *testing.T) {
+	// Version 1 has no flags byte
+	data := make([]byte, 28)
+	copy(data, Magic)
+	data[3] = 1
+	data[4] = byte(ModeInt)
+	data[5] = 8
+	data[15] = 42 // First
+	data[23] = 43 // Second
+	data[27] = 10 // Count
+
+	h, size, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if size != 28 {
+		t.Errorf("expected header size 28, got %d", size)
+	}
+
+	expected := Header{Mode: ModeInt, RiceParam: 8, First: 42, Second: 43, ValueCount: 10}
+	if *h != expected {
+		t.Errorf("expected %+v, got %+v", expected, *h)
+	}
+}
+
+func TestHeader_Flags(t *testing.T) {
+	original := &Header{Flags: FlagChecksum, Mode: ModeInt, RiceParam: 4, ValueCount: 2}
+
+	decoded, size, err := Unmarshal(original.Marshal())
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if size != HeaderSize || decoded.Flags != FlagChecksum {
+		t.Errorf("expected size %d with flags %#x, got %d with %#x", HeaderSize, FlagChecksum, size, decoded.Flags)
+	}
+
+	decoded.Flags = 0x80
+	if err := decoded.Validate(); err == nil {
+		t.Error("expected error for unknown flags")
+	}
+}
+
+func TestHeader_UnmarshalVersion2(t *testing.T) {
+	// Version 2 has a flags byte and the narrow fields of version 1
+	data := make([]byte, 29)
+	copy(data, Magic)
+	data[3] = 2
+	data[4] = FlagChecksum
+	data[5] = byte(ModeFloat)
+	data[6] = 4
+	data[7] = 2
+	data[8] = 1
+	data[28] = 100 // Count
+
+	h, size, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if size != 29 {
+		t.Errorf("expected header size 29, got %d", size)
+	}
+
+	expected := Header{Flags: FlagChecksum, Mode: ModeFloat, RiceParam: 4, ALPExp: 2, ALPFactor: 1, ValueCount: 100}
+	if *h != expected {
+		t.Errorf("expected %+v, got %+v", expected, *h)
+	}
+}
+
+func TestHeader_WideFields(t *testing.T) {
+	original := &Header{
+		Mode:       ModeInt,
+		RiceParam:  300,
+		First:      -1,
+		Second:     1,
+		ValueCount: 1<<31 - 1,
+	}
+
+	decoded, _, err := Unmarshal(original.Marshal())
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if *decoded != *original {
+		t.Errorf("expected %+v, got %+v", *original, *decoded)
+	}
+}
+
+func TestHeader_ValidateRiceParamLimit(t *testing.T) {
+	limit := MaxRiceParam
+	h := &Header{Mode: ModeInt, RiceParam: limit, ValueCount: 2}
+	if err := h.Validate(); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+
+	if strconv.IntSize < 64 {
+		return
+	}
+	h.RiceParam = limit + 1
+	if err := h.Validate(); err == nil {
+		t.Error("expected error above the limit")
+	}
+}
+
+func TestHeader_CompactRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		header Header
+	}{
+		{"power of two rice", Header{Mode: ModeInt, RiceParam: 8, First: 1700000000, Second: 1700000060, ValueCount: 60}},
+		{"non power of two rice", Header{Mode: ModeInt, RiceParam: 12, First: -5, Second: 7, ValueCount: 10}},
+		{"wide rice", Header{Mode: ModeInt, RiceParam: 1 << 20, ValueCount: 3}},
+		{"float", Header{Flags: FlagChecksum, Mode: ModeFloat, RiceParam: 1, ALPExp: 2, ALPFactor: 1, First: 2135, Second: 2140, ValueCount: 12}},
+		{"no rice", Header{Mode: ModeFloatXOR, First: -4616189618054758400, ValueCount: 1 << 30}},
+		{"blocked", Header{Mode: ModeBlocked, ValueCount: 100000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.header.MarshalCompact()
+			if data == nil {
+				t.Fatal("expected a compact header")
+			}
+
+			decoded, size, err := Unmarshal(append(data, 0xAA))
+			if err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+
+			if size != len(data) {
+				t.Errorf("size: expected %d, got %d", len(data), size)
+			}
+
+			if *decoded != tt.header {
+				t.Errorf("expected %+v, got %+v", tt.header, *decoded)
+			}
+		})
+	}
+}
+
+func TestHeader_CompactIsSmall(t *testing.T) {
+	h := &Header{Mode: ModeFloat, RiceParam: 4, ALPExp: 2, First: 2135, Second: 2140, ValueCount: 60}
+
+	if size := len(h.MarshalCompact()); size > 14 {
+		t.Errorf("expected a compact header of at most 14 bytes, got %d", size)
+	}
+}
+
+func TestHeader_CompactTruncated(t *testing.T) {
+	data := (&Header{Mode: ModeFloat, RiceParam: 12, ALPExp: 2, First: 2135, Second: 2140, ValueCount: 60}).MarshalCompact()
+
+	for n := len(Magic) + 1; n < len(data); n++ {
+		if _, _, err := Unmarshal(data[:n]); err == nil {
+			t.Errorf("expected error for header truncated to %d bytes", n)
+		}
+	}
+}
+
+func TestHeader_CompactRejectsOldVersion(t *testing.T) {
+	data := (&Header{Mode: ModeInt, RiceParam: 4, ValueCount: 3}).MarshalCompact()
+	data[3] = 3 | compactBit
+
+	if _, _, err := Unmarshal(data); err == nil {
+		t.Error("expected error for a compact header before version 4")
+	}
+}
+
+func TestHeader_CompactWideMode(t *testing.T) {
+	if data := (&Header{Mode: 16, ValueCount: 2}).MarshalCompact(); data != nil {
+		t.Errorf("expected nil for a mode outside the nibble, got %v", data)
+	}
+}
+
+func TestHeader_CheckPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  Header
+		payload int
+		wantErr bool
+	}{
+		{"rice", Header{Mode: ModeInt, RiceParam: 1, ValueCount: 18}, 3, false},
+		{"rice short", Header{Mode: ModeFloat, RiceParam: 1, ValueCount: 19}, 3, true},
+		{"xor", Header{Mode: ModeFloatXOR, ValueCount: 17}, 2, false},
+		{"xor short", Header{Mode: ModeFloatXOR, ValueCount: 18}, 2, true},
+		{"alp-rd short", Header{Mode: ModeFloatRD, ValueCount: 2}, 16, true},
+		{"fastlanes", Header{Mode: ModeIntFastLanes, ValueCount: 1026}, 1, false},
+		{"fastlanes short", Header{Mode: ModeFloatFastLanes, ValueCount: 1027}, 1, true},
+		{"pfor short", Header{Mode: ModeIntPFOR, ValueCount: 3}, 1, true},
+		{"for short", Header{Mode: ModeIntFOR, ValueCount: 1}, 0, true},
+		{"zero width", Header{Mode: ModeIntFOR, ValueCount: MaxSegmentValues}, 1, false},
+		{"blocked", Header{Mode: ModeBlocked, ValueCount: 1 << 20}, 0, false},
+		{"empty", Header{Mode: ModeFloat, ValueCount: 0}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.header.CheckPayload(make([]byte, tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
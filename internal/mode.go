@@ -10,6 +10,49 @@ const (
 	// ModeInt uses simple delta: value[i] - value[i-1]
 	// Best for: Monotonically increasing/decreasing integers
 	ModeInt
+
+	// ModeFloatXOR stores raw IEEE-754 bits XORed with the previous value.
+	// Fallback for float64 data without usable decimal structure
+	ModeFloatXOR
+
+	// ModeFloatRD uses ALP-RD: dictionary-encoded left bits + bit-packed right bits.
+	// Best for: High-precision doubles (division results, trig, ML outputs)
+	ModeFloatRD
+
+	// ModeBlocked splits the series into blocks that are encoded independently,
+	// each with its own mode and parameters. Best for: Long series whose
+	// precision or volatility drifts
+	ModeBlocked
+
+	// ModeTimeSeries pairs an int64 timestamp column with a float64 value
+	// column of the same length, each encoded on its own path
+	ModeTimeSeries
+
+	// ModeFrame holds any number of named columns of the same length, each
+	// with its own mode and parameters
+	ModeFrame
+
+	// ModeIntFOR subtracts the block minimum and bit-packs the offsets at the
+	// width of max-min. Best for: Bounded integers without a trend (status
+	// codes, queue depths, percentages)
+	ModeIntFOR
+
+	// ModeIntPFOR is ModeInt with the residuals bit-packed at the width that
+	// minimizes the encoded size and the rest patched as exceptions. Best for:
+	// Mostly-small residuals with outliers (latencies)
+	ModeIntPFOR
+
+	// ModeFloatPFOR is ModeFloat with the residuals coded as in ModeIntPFOR
+	ModeFloatPFOR
+
+	// ModeIntFastLanes is ModeInt with the residuals bit-packed in the
+	// FastLanes interleaved 1024-value layout. Best for: Series read far more
+	// often than written, where decode speed matters more than size
+	ModeIntFastLanes
+
+	// ModeFloatFastLanes is ModeFloat with the residuals coded as in
+	// ModeIntFastLanes
+	ModeFloatFastLanes
 )
 
 // ModeFromByte converts a byte to Mode
@@ -21,3 +64,36 @@ func ModeFromByte(b byte) Mode {
 func (m Mode) Byte() byte {
 	return byte(m)
 }
+
+// IsFloat reports whether the mode decodes to float64 values
+func (m Mode) IsFloat() bool {
+	return m == ModeFloat || m == ModeFloatXOR || m == ModeFloatRD || m == ModeFloatPFOR || m == ModeFloatFastLanes
+}
+
+// IsInt reports whether the mode decodes to int64 values
+func (m Mode) IsInt() bool {
+	return m == ModeInt || m == ModeIntFOR || m == ModeIntPFOR || m == ModeIntFastLanes
+}
+
+// UsesRice reports whether the payload is Golomb-Rice coded
+func (m Mode) UsesRice() bool {
+	return m == ModeFloat || m == ModeInt
+}
+
+// UsesALP reports whether the values are ALP-scaled, with an exponent and a
+// factor in the header
+func (m Mode) UsesALP() bool {
+	return m == ModeFloat || m == ModeFloatPFOR || m == ModeFloatFastLanes
+}
+
+// UsesPFOR reports whether the residuals are coded with patched
+// frame-of-reference instead of Golomb-Rice
+func (m Mode) UsesPFOR() bool {
+	return m == ModeIntPFOR || m == ModeFloatPFOR
+}
+
+// UsesFastLanes reports whether the residuals are coded in the FastLanes
+// layout instead of Golomb-Rice
+func (m Mode) UsesFastLanes() bool {
+	return m == ModeIntFastLanes || m == ModeFloatFastLanes
+}
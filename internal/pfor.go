@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Patched frame-of-reference payload format (ModeIntPFOR, ModeFloatPFOR),
+// coding the zigzagged residuals in place of the Golomb-Rice stream:
+// uvarint  Exception count
+// 1B       Bit width w (0-64)
+// ...      Low w bits of every residual, bit-packed
+// ...      Exceptions, in position order: uvarint position gap, uvarint high
+//          bits (residual >> w)
+//
+// The width is the one that minimizes the encoded size, weighing the bits every
+// value spends at that width against the bytes each outlier above it costs. It
+// is not tied to a fixed share of the values: a predictor turns each spike
+// into a few wide residuals, so outliers can exceed 10% of the residuals and
+// still be cheaper to patch. The outliers pay a few bytes each instead of
+// widening every value (as plain bit-packing would) or a long unary run (as
+// Golomb-Rice would).
+
+// PFOREncode bit-packs input at the width that minimizes the encoded size and
+// patches the values that don't fit
+func PFOREncode(input []uint64) []byte {
+	width := pforWidth(input)
+
+	var exceptions []Exception
+	var w bitWriter
+	for i, v := range input {
+		w.writeBits(v, width)
+		if bits.Len64(v) > width {
+			exceptions = append(exceptions, Exception{Position: i, Bits: v >> width})
+		}
+	}
+
+	buf := binary.AppendUvarint(nil, uint64(len(exceptions)))
+	buf = append(buf, byte(width))
+	buf = append(buf, w.bytes()...)
+
+	prev := 0
+	for _, exc := range exceptions {
+		buf = binary.AppendUvarint(buf, uint64(exc.Position-prev))
+		buf = binary.AppendUvarint(buf, exc.Bits)
+		prev = exc.Position
+	}
+	return buf
+}
+
+// PFORDecode reverses PFOREncode and returns the number of bytes consumed, so
+// that callers can locate sections stored after it
+func PFORDecode(data []byte, valueCount int) ([]uint64, int, error) {
+	count, offset := binary.Uvarint(data)
+	if offset <= 0 {
+		return nil, 0, errors.New("pfor: invalid exception count")
+	}
+	if count > uint64(valueCount) {
+		return nil, 0, fmt.Errorf("pfor: %d exceptions for %d values", count, valueCount)
+	}
+
+	if offset >= len(data) {
+		return nil, 0, errors.New("pfor: data too short")
+	}
+	width := int(data[offset])
+	offset++
+	if width > 64 {
+		return nil, 0, fmt.Errorf("pfor: bit width %d exceeds 64", width)
+	}
+
+	if err := checkPacked(len(data)-offset, valueCount, width); err != nil {
+		return nil, 0, fmt.Errorf("pfor: %w", err)
+	}
+	packedLen := (uint64(width)*uint64(valueCount) + 7) / 8
+
+	r := bitReader{data: data[offset : offset+int(packedLen)]}
+	result := make([]uint64, valueCount)
+	for i := range result {
+		v, err := r.readBits(width)
+		if err != nil {
+			return nil, 0, fmt.Errorf("pfor: %w", err)
+		}
+		result[i] = v
+	}
+	offset += int(packedLen)
+
+	pos := 0
+	for i := range count {
+		gap, n := binary.Uvarint(data[offset:])
+		if n <= 0 {
+			return nil, 0, fmt.Errorf("pfor: exception %d: invalid position", i)
+		}
+		offset += n
+		if (i > 0 && gap == 0) || gap >= uint64(valueCount-pos) {
+			return nil, 0, fmt.Errorf("pfor: exception %d: position out of range", i)
+		}
+		pos += int(gap)
+
+		high, n := binary.Uvarint(data[offset:])
+		if n <= 0 {
+			return nil, 0, fmt.Errorf("pfor: exception %d: invalid value", i)
+		}
+		offset += n
+		if width == 64 || high == 0 || high>>(64-width) != 0 {
+			return nil, 0, fmt.Errorf("pfor: exception %d: value does not fit 64 bits", i)
+		}
+		result[pos] |= high << width
+	}
+
+	return result, offset, nil
+}
+
+// PFORSize returns the number of bits PFOREncode would produce for input,
+// without encoding it
+func PFORSize(input []uint64) uint64 {
+	return pforSize(input, pforWidth(input))
+}
+
+// pforSize returns the number of bits of input bit-packed at width, with the
+// values that don't fit patched
+func pforSize(input []uint64, width int) uint64 {
+	var count, patched uint64
+	prev := 0
+	for i, v := range input {
+		if bits.Len64(v) > width {
+			patched += uint64(uvarintLen(uint64(i-prev)) + uvarintLen(v>>width))
+			count++
+			prev = i
+		}
+	}
+
+	packed := (uint64(width)*uint64(len(input)) + 7) / 8
+	return 8 * (uint64(uvarintLen(count)+1) + packed + patched)
+}
+
+// pforWidth returns the bit width that minimizes the encoded size of input,
+// estimated from the distribution of bit lengths with one-byte position gaps
+func pforWidth(input []uint64) int {
+	var lengths [65]uint64
+	for _, v := range input {
+		lengths[bits.Len64(v)]++
+	}
+
+	best, bestSize := 64, uint64(64)*uint64(len(input))
+	for width := range 64 {
+		size := uint64(width) * uint64(len(input))
+		for l := width + 1; l <= 64; l++ {
+			// Gap byte plus the high bits, 7 per uvarint byte
+			size += lengths[l] * 8 * uint64(1+(l-width+6)/7)
+		}
+		if size < bestSize {
+			best, bestSize = width, size
+		}
+	}
+	return best
+}
+
+// uvarintLen returns the number of bytes binary.AppendUvarint writes for v
+func uvarintLen(v uint64) int {
+	return (bits.Len64(v|1) + 6) / 7
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestPFOR_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	latencies := make([]uint64, 1000)
+	for i := range latencies {
+		latencies[i] = uint64(rng.Intn(64))
+		if rng.Intn(20) == 0 {
+			latencies[i] = uint64(rng.Intn(1 << 20))
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input []uint64
+	}{
+		{"empty", nil},
+		{"zeros", []uint64{0, 0, 0, 0}},
+		{"single outlier", []uint64{3, 1, 2, 0, 1 << 40, 2, 3, 1, 0, 2}},
+		{"first value outlier", []uint64{1 << 30, 1, 2, 3, 1, 2, 3, 1}},
+		{"full width", []uint64{math.MaxUint64, 0, math.MaxUint64}},
+		{"latencies", latencies},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := PFOREncode(tt.input)
+			if size := PFORSize(tt.input); size != 8*uint64(len(data)) {
+				t.Errorf("expected size %d bits, got %d", 8*len(data), size)
+			}
+
+			// Trailing bytes belong to the next section
+			decoded, consumed, err := PFORDecode(append(data, 0xAA, 0xBB), len(tt.input))
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if consumed != len(data) {
+				t.Errorf("expected %d bytes consumed, got %d", len(data), consumed)
+			}
+			if !slices.Equal(decoded, tt.input) {
+				t.Errorf("expected %v, got %v", tt.input, decoded)
+			}
+		})
+	}
+}
+
+func TestPFOR_Width(t *testing.T) {
+	// Outliers are patched instead of widening every value
+	input := make([]uint64, 100)
+	for i := range input {
+		input[i] = uint64(i % 16)
+	}
+	input[10], input[50] = 1<<40, 1<<33
+
+	data := PFOREncode(input)
+	if width := data[1]; width != 4 {
+		t.Errorf("expected width 4, got %d", width)
+	}
+	if len(data) > 100*4/8+20 {
+		t.Errorf("expected about %d bytes, got %d", 100*4/8, len(data))
+	}
+}
+
+func TestPFOR_WidthMinimizesSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+
+	// Residuals of a spiky series: every spike leaves three wide residuals, so
+	// 15% of them are outliers and a 90% coverage width would be far too wide
+	spiky := make([]uint64, 1000)
+	for i := range spiky {
+		spiky[i] = uint64(rng.Intn(256))
+	}
+	for i := 0; i < len(spiky)-2; i += 20 {
+		spiky[i], spiky[i+1], spiky[i+2] = 1<<30+uint64(i), 1<<31+uint64(i), 1<<30
+	}
+
+	uniform := make([]uint64, 1000)
+	for i := range uniform {
+		uniform[i] = uint64(rng.Intn(1 << 12))
+	}
+
+	for name, input := range map[string][]uint64{"spiky": spiky, "uniform": uniform} {
+		width := pforWidth(input)
+		best := pforSize(input, width)
+		for w := range 65 {
+			if size := pforSize(input, w); size < best {
+				t.Errorf("%s: width %d takes %d bits, the chosen width %d takes %d", name, w, size, width, best)
+			}
+		}
+		if name == "spiky" && width > 8 {
+			t.Errorf("spiky: expected the outliers to be patched, got width %d", width)
+		}
+	}
+}
+
+func TestPFORDecode_Invalid(t *testing.T) {
+	input := []uint64{3, 1, 2, 0, 1 << 40, 2, 3, 1}
+	data := PFOREncode(input)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"too many exceptions", append([]byte{9}, data[1:]...)},
+		{"width too large", append([]byte{data[0], 65}, data[2:]...)},
+		{"truncated packing", data[:3]},
+		{"truncated exceptions", data[:len(data)-1]},
+		{"position out of range", append(slices.Clone(data[:len(data)-7]), 8, 1)},
+		{"zero high bits", append(slices.Clone(data[:len(data)-7]), 4, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := PFORDecode(tt.data, len(input)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
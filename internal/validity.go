@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Validity section format (starts the payload of a FlagNullable blob):
+// uvarint  series length, nulls included
+// 1B       encoding: validityRuns or validityBitmap
+// validityRuns:
+//   uvarint  run count
+//   uvarint  run lengths, alternating present/null, starting with present
+//            (the first run may be empty)
+//   Null runs cost a byte or two however long they are, so the length of a
+//   run-encoded series is capped at maxRunsLength.
+// validityBitmap:
+//   ceil(length/8) bytes, bit i (most significant first) set when value i is
+//   present
+//
+// The encoder writes whichever encoding is smaller.
+
+const (
+	validityRuns   = 0
+	validityBitmap = 1
+)
+
+// maxRunsLength is the largest series length the run encoding is used for;
+// longer series fall back to the bitmap, whose size bounds their length.
+const maxRunsLength = 1 << 24
+
+// MarshalValidity serializes which values of a series are present.
+func MarshalValidity(valid []bool) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(valid)))
+
+	runs := validityRunLengths(valid)
+	runBytes := binary.AppendUvarint(nil, uint64(len(runs)))
+	for _, run := range runs {
+		runBytes = binary.AppendUvarint(runBytes, uint64(run))
+	}
+
+	bitmapSize := (len(valid) + 7) / 8
+	if len(runBytes) <= bitmapSize && len(valid) <= maxRunsLength {
+		buf = append(buf, validityRuns)
+		return append(buf, runBytes...)
+	}
+
+	buf = append(buf, validityBitmap)
+	bitmap := make([]byte, bitmapSize)
+	for i, v := range valid {
+		if v {
+			bitmap[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return append(buf, bitmap...)
+}
+
+// UnmarshalValidity parses a validity section and returns the presence of each
+// value along with the number of bytes consumed. present is the number of
+// present values the header counts; a section marking a different number is
+// rejected before anything is allocated.
+func UnmarshalValidity(data []byte, present int) ([]bool, int, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || length > uint64(maxInt) {
+		return nil, 0, errors.New("validity section: invalid length")
+	}
+	offset := n
+
+	if offset >= len(data) {
+		return nil, 0, errors.New("validity section: unexpected end of data")
+	}
+	encoding := data[offset]
+	offset++
+
+	switch encoding {
+	case validityRuns:
+		if length > maxRunsLength {
+			return nil, 0, fmt.Errorf("validity section: %d values exceed the run encoding limit of %d", length, maxRunsLength)
+		}
+		count, n := binary.Uvarint(data[offset:])
+		if n <= 0 || count > uint64(len(data)-offset) {
+			return nil, 0, errors.New("validity section: invalid run count")
+		}
+		offset += n
+
+		runs := make([]uint64, count)
+		total, marked := uint64(0), uint64(0)
+		for i := range runs {
+			run, n := binary.Uvarint(data[offset:])
+			if n <= 0 || run > length-total {
+				return nil, 0, errors.New("validity section: invalid run length")
+			}
+			runs[i] = run
+			total += run
+			if i%2 == 0 {
+				marked += run
+			}
+			offset += n
+		}
+		if total != length {
+			return nil, 0, fmt.Errorf("validity section: runs cover %d of %d values", total, length)
+		}
+		if marked != uint64(present) {
+			return nil, 0, fmt.Errorf("validity section marks %d values present, header says %d", marked, present)
+		}
+
+		valid := make([]bool, 0, length)
+		for i, run := range runs {
+			for range run {
+				valid = append(valid, i%2 == 0)
+			}
+		}
+		return valid, offset, nil
+
+	case validityBitmap:
+		size := (length + 7) / 8
+		if size > uint64(len(data)-offset) {
+			return nil, 0, errors.New("validity section: truncated bitmap")
+		}
+
+		valid := make([]bool, length)
+		marked := 0
+		for i := range valid {
+			valid[i] = data[offset+i/8]&(0x80>>(i%8)) != 0
+			if valid[i] {
+				marked++
+			}
+		}
+		if marked != present {
+			return nil, 0, fmt.Errorf("validity section marks %d values present, header says %d", marked, present)
+		}
+		return valid, offset + int(size), nil
+
+	default:
+		return nil, 0, fmt.Errorf("validity section: unknown encoding %d", encoding)
+	}
+}
+
+// validityRunLengths returns the alternating present/null run lengths of
+// valid, starting with a (possibly empty) present run
+func validityRunLengths(valid []bool) []int {
+	var runs []int
+	current, run := true, 0
+	for _, v := range valid {
+		if v != current {
+			runs = append(runs, run)
+			current, run = v, 0
+		}
+		run++
+	}
+	if run > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+func TestValidity_RoundTrip(t *testing.T) {
+	alternating := make([]bool, 100)
+	for i := range alternating {
+		alternating[i] = i%2 == 0
+	}
+	sparse := make([]bool, 10000)
+	sparse[5000] = true
+	halves := make([]bool, 2000)
+	for i := range 1000 {
+		halves[i] = true
+	}
+
+	tests := []struct {
+		name     string
+		valid    []bool
+		encoding byte
+	}{
+		{"empty", nil, validityBitmap},
+		{"all present", []bool{true, true, true}, validityBitmap},
+		{"leading null", []bool{false, true, true, false}, validityBitmap},
+		{"alternating", alternating, validityBitmap},
+		{"sparse", sparse, validityRuns},
+		{"halves", halves, validityRuns},
+		{"all null", make([]bool, 64), validityRuns},
+		{"past run limit", make([]bool, maxRunsLength+1), validityBitmap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := MarshalValidity(tt.valid)
+			data = append(data, 0xAA) // trailing payload must not be consumed
+
+			present := 0
+			for _, v := range tt.valid {
+				if v {
+					present++
+				}
+			}
+			valid, n, err := UnmarshalValidity(data, present)
+			if err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if n != len(data)-1 {
+				t.Errorf("expected %d bytes consumed, got %d", len(data)-1, n)
+			}
+			if !slices.Equal(valid, tt.valid) {
+				t.Errorf("expected %v, got %v", tt.valid, valid)
+			}
+
+			// The smaller encoding is chosen; it follows the uvarint length
+			_, lengthSize := binary.Uvarint(data)
+			if data[lengthSize] != tt.encoding {
+				t.Errorf("expected encoding %d, got %d", tt.encoding, data[lengthSize])
+			}
+		})
+	}
+}
+
+func TestValidity_Invalid(t *testing.T) {
+	// A huge null run followed by a present one: two bytes of runs that
+	// would materialise 2^60 values
+	huge := binary.AppendUvarint(nil, 1<<60+1)
+	huge = append(huge, validityRuns, 3, 0)
+	huge = binary.AppendUvarint(huge, 1<<60)
+	huge = append(huge, 1)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		present int
+	}{
+		{"empty", nil, 0},
+		{"missing encoding", []byte{3}, 0},
+		{"unknown encoding", []byte{3, 7}, 0},
+		{"runs short of length", []byte{3, validityRuns, 2, 1, 1}, 1},
+		{"runs past length", []byte{3, validityRuns, 2, 2, 2}, 2},
+		{"truncated runs", []byte{3, validityRuns, 2, 1}, 1},
+		{"runs past limit", huge, 1},
+		{"runs mark too many present", []byte{3, validityRuns, 2, 2, 1}, 1},
+		{"runs mark too few present", []byte{3, validityRuns, 2, 2, 1}, 3},
+		{"bitmap marks too many present", []byte{3, validityBitmap, 0xE0}, 2},
+		{"truncated bitmap", []byte{9, validityBitmap, 0xFF}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := UnmarshalValidity(tt.data, tt.present); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
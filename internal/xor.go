@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// XOR payload format (ModeFloatXOR), Gorilla-style:
+// The raw bits of the first value are stored in the header. Every following
+// value is XORed with its predecessor and written as one of:
+// '0'                                       identical to the previous value
+// '10' + meaningful bits                    fits the previous leading/trailing window
+// '11' + 6b leading zeros + 6b (length-1) + meaningful bits
+
+// XOREncode encodes the raw IEEE-754 bits of input[1:] as XORs against their
+// predecessor. It is lossless for every bit pattern, including NaN payloads.
+func XOREncode(input []float64) []byte {
+	var w bitWriter
+	if len(input) < 2 {
+		return w.bytes()
+	}
+
+	prev := math.Float64bits(input[0])
+	prevLeading, prevTrailing := -1, 0
+
+	for _, val := range input[1:] {
+		cur := math.Float64bits(val)
+		xor := cur ^ prev
+		prev = cur
+
+		if xor == 0 {
+			w.writeBit(0)
+			continue
+		}
+
+		leading := bits.LeadingZeros64(xor)
+		trailing := bits.TrailingZeros64(xor)
+
+		if prevLeading >= 0 && leading >= prevLeading && trailing >= prevTrailing {
+			w.writeBits(0b10, 2)
+			w.writeBits(xor>>prevTrailing, 64-prevLeading-prevTrailing)
+			continue
+		}
+
+		// Leading zeros are capped at 63 so they fit in 6 bits; xor != 0 anyway
+		length := 64 - leading - trailing
+		w.writeBits(0b11, 2)
+		w.writeBits(uint64(leading), 6)
+		w.writeBits(uint64(length-1), 6)
+		w.writeBits(xor>>trailing, length)
+		prevLeading, prevTrailing = leading, trailing
+	}
+
+	return w.bytes()
+}
+
+// XORDecode reverses XOREncode. first holds the raw bits of the first value and
+// valueCount includes it.
+func XORDecode(data []byte, first uint64, valueCount int) ([]float64, error) {
+	if valueCount < 0 {
+		return nil, errors.New("valueCount must not be negative")
+	}
+	if valueCount == 0 {
+		return []float64{}, nil
+	}
+	// Every value after the first takes at least one bit
+	if uint64(valueCount-1) > 8*uint64(len(data)) {
+		return nil, errors.New("data too short for value count")
+	}
+
+	result := make([]float64, valueCount)
+	result[0] = math.Float64frombits(first)
+
+	r := bitReader{data: data}
+	prev := first
+	prevLeading, prevTrailing := -1, 0
+
+	for i := 1; i < valueCount; i++ {
+		control, err := r.readBit()
+		if err != nil {
+			return nil, err
+		}
+
+		if control == 1 {
+			reuse, err := r.readBit()
+			if err != nil {
+				return nil, err
+			}
+
+			if reuse == 1 {
+				leading, err := r.readBits(6)
+				if err != nil {
+					return nil, err
+				}
+				length, err := r.readBits(6)
+				if err != nil {
+					return nil, err
+				}
+				length++
+				if int(leading)+int(length) > 64 {
+					return nil, errors.New("invalid xor window")
+				}
+				prevLeading = int(leading)
+				prevTrailing = 64 - prevLeading - int(length)
+			} else if prevLeading < 0 {
+				return nil, errors.New("xor window reused before being set")
+			}
+
+			meaningful, err := r.readBits(64 - prevLeading - prevTrailing)
+			if err != nil {
+				return nil, err
+			}
+			prev ^= meaningful << prevTrailing
+		}
+
+		result[i] = math.Float64frombits(prev)
+	}
+
+	return result, nil
+}
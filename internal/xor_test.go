@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestXOR_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []float64
+	}{
+		{"single", []float64{1e-20}},
+		{"repeated", []float64{0.1 + 0.2, 0.1 + 0.2, 0.1 + 0.2}},
+		{"tiny and huge", []float64{1e-20, 1e300, -1e-300, 5e-324, math.MaxFloat64}},
+		{"divisions", []float64{1.0 / 3, 2.0 / 3, 1.0 / 7, 22.0 / 7, math.Pi, math.E}},
+		{"window reuse", []float64{1.0, 1.5, 1.25, 1.75, 1.125}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := XOREncode(tt.input)
+
+			decoded, err := XORDecode(data, math.Float64bits(tt.input[0]), len(tt.input))
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+
+			for i := range tt.input {
+				if math.Float64bits(decoded[i]) != math.Float64bits(tt.input[i]) {
+					t.Errorf("round-trip[%d]: expected %v, got %v", i, tt.input[i], decoded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestXOREncode_IdenticalValues(t *testing.T) {
+	input := []float64{42.5, 42.5, 42.5, 42.5, 42.5, 42.5, 42.5, 42.5, 42.5}
+
+	// One zero bit per repeated value
+	data := XOREncode(input)
+	if len(data) != 1 || data[0] != 0 {
+		t.Errorf("expected a single zero byte, got %x", data)
+	}
+}
+
+func TestXORDecode_Truncated(t *testing.T) {
+	input := []float64{1.0 / 3, 2.0 / 3, 1.0 / 7}
+	data := XOREncode(input)
+
+	_, err := XORDecode(data[:len(data)-2], math.Float64bits(input[0]), len(input))
+	if err == nil {
+		t.Error("expected error for truncated data, got nil")
+	}
+}
+
+func TestXORDecode_Empty(t *testing.T) {
+	decoded, err := XORDecode(XOREncode(nil), 0, 0)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if len(decoded) != 0 {
+		t.Errorf("expected no values, got %v", decoded)
+	}
+}
@@ -0,0 +1,130 @@
+package alpine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// errNullable is returned by the decode APIs that have no way to report nulls
+var errNullable = errors.New("series has null values: use DecodeNullableFloat or DecodeNullableInt")
+
+// WithValidity marks which values are present; valid[i] false makes value i
+// null, and whatever data[i] holds is ignored. Only the present values go
+// through the compression pipeline, so nulls don't disturb the predictor. A
+// series without nulls is encoded exactly as if no validity were given.
+func (e *FloatEncoder) WithValidity(valid []bool) *FloatEncoder {
+	e.valid = valid
+	return e
+}
+
+// WithValidity marks which values are present; valid[i] false makes value i
+// null, and whatever data[i] holds is ignored. Only the present values go
+// through the compression pipeline, so nulls don't disturb the predictor. A
+// series without nulls is encoded exactly as if no validity were given.
+func (e *IntEncoder) WithValidity(valid []bool) *IntEncoder {
+	e.valid = valid
+	return e
+}
+
+// DecodeNullableFloat decodes a float64 series along with the presence of each
+// value. Null values are returned as 0. Blobs without nulls report every value
+// as present.
+func (d *Decoder) DecodeNullableFloat() ([]float64, []bool, error) {
+	return decodeNullable(d, decodeFloatSegment)
+}
+
+// DecodeNullableInt decodes an int64 series along with the presence of each
+// value. Null values are returned as 0. Blobs without nulls report every value
+// as present.
+func (d *Decoder) DecodeNullableInt() ([]int64, []bool, error) {
+	return decodeNullable(d, decodeIntSegment)
+}
+
+// decodeNullable decodes the present values with decode and spreads them over
+// the positions marked valid
+func decodeNullable[T any](d *Decoder, decode func(*internal.Header, []byte) ([]T, error)) ([]T, []bool, error) {
+	header, _, err := d.blockIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	present, err := decodeRange(d, 0, header.ValueCount, decode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if d.valid == nil {
+		return present, validityOf(d), nil
+	}
+
+	values := make([]T, len(d.valid))
+	next := 0
+	for i, v := range d.valid {
+		if v {
+			values[i] = present[next]
+			next++
+		}
+	}
+	return values, validityOf(d), nil
+}
+
+// validityOf returns a copy of the validity of the parsed series of d, with
+// every value present if it has no nulls
+func validityOf(d *Decoder) []bool {
+	// The cached validity backs later calls, so hand out a copy
+	if d.valid != nil {
+		return slices.Clone(d.valid)
+	}
+	valid := make([]bool, d.header.ValueCount)
+	for i := range valid {
+		valid[i] = true
+	}
+	return valid
+}
+
+// splitNulls returns the present values of data and the validity section
+// describing where the nulls were. Without nulls, data is returned as is and
+// the section is nil.
+func splitNulls[T any](data []T, valid []bool) ([]T, []byte, error) {
+	if valid == nil {
+		return data, nil, nil
+	}
+	if len(valid) != len(data) {
+		return nil, nil, fmt.Errorf("validity has %d entries for %d values", len(valid), len(data))
+	}
+	if !slices.Contains(valid, false) {
+		return data, nil, nil
+	}
+
+	present := make([]T, 0, countValid(valid))
+	for i, v := range valid {
+		if v {
+			present = append(present, data[i])
+		}
+	}
+	return present, internal.MarshalValidity(valid), nil
+}
+
+// withValidity prepends a validity section to payload and flags the header,
+// or returns payload unchanged when the section is nil
+func withValidity(header *internal.Header, validity, payload []byte) []byte {
+	if validity == nil {
+		return payload
+	}
+	header.Flags |= internal.FlagNullable
+	return append(validity, payload...)
+}
+
+// countValid returns the number of present values
+func countValid(valid []bool) int {
+	count := 0
+	for _, v := range valid {
+		if v {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,237 @@
+package alpine
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ach968/alpine/internal"
+)
+
+func TestFloatEncoder_WithValidity(t *testing.T) {
+	data := []float64{21.35, 0, 21.40, 21.38, 0, 0, 21.41, 21.45}
+	valid := []bool{true, false, true, true, false, false, true, true}
+
+	encoded, err := NewFloatEncoder(data).WithValidity(valid).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	values, gotValid, err := NewDecoder(encoded).DecodeNullableFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(gotValid, valid) {
+		t.Errorf("expected validity %v, got %v", valid, gotValid)
+	}
+	if !slices.Equal(values, data) {
+		t.Errorf("expected %v, got %v", data, values)
+	}
+}
+
+func TestFloatEncoder_NullsDoNotDisturbPredictor(t *testing.T) {
+	// Nulls stored as arbitrary garbage would wreck the residuals if they went
+	// through the pipeline
+	data := make([]float64, 1000)
+	valid := make([]bool, len(data))
+	for i := range data {
+		if i%7 == 3 {
+			data[i] = -9.99e300
+			continue
+		}
+		data[i] = float64(i) * 0.25
+		valid[i] = true
+	}
+
+	withNulls, err := NewFloatEncoder(data).WithValidity(valid).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	present, _, _ := splitNulls(data, valid)
+	dense, err := NewFloatEncoder(present).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if overhead := len(withNulls) - len(dense); overhead > len(data)/8+8 {
+		t.Errorf("validity costs %d bytes over the dense encoding of %d bytes", overhead, len(dense))
+	}
+
+	values, gotValid, err := NewDecoder(withNulls).DecodeNullableFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	for i := range data {
+		if gotValid[i] != valid[i] || valid[i] && values[i] != data[i] || !valid[i] && values[i] != 0 {
+			t.Fatalf("index %d: expected (%v, %v), got (%v, %v)", i, data[i], valid[i], values[i], gotValid[i])
+		}
+	}
+}
+
+func TestIntEncoder_WithValidity(t *testing.T) {
+	blockedData := make([]int64, 300)
+	blockedValid := make([]bool, len(blockedData))
+	for i := range blockedData {
+		if i%50 != 0 {
+			blockedData[i] = int64(i * 3)
+			blockedValid[i] = true
+		}
+	}
+
+	tests := []struct {
+		name  string
+		data  []int64
+		valid []bool
+	}{
+		{"sparse nulls", []int64{100, 110, 0, 130, 140}, []bool{true, true, false, true, true}},
+		{"all null", []int64{0, 0, 0}, []bool{false, false, false}},
+		{"single present", []int64{0, 42, 0}, []bool{false, true, false}},
+		{"blocked", blockedData, blockedValid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := NewIntEncoder(tt.data).WithValidity(tt.valid).WithBlockSize(64).WithChecksum().Encode()
+			if err != nil {
+				t.Fatalf("encode error: %v", err)
+			}
+
+			values, valid, err := NewDecoder(encoded).DecodeNullableInt()
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if !slices.Equal(valid, tt.valid) {
+				t.Errorf("expected validity %v, got %v", tt.valid, valid)
+			}
+			if !slices.Equal(values, tt.data) {
+				t.Errorf("expected %v, got %v", tt.data, values)
+			}
+		})
+	}
+}
+
+func TestEncoder_ValidityWithoutNulls(t *testing.T) {
+	data := []int64{1, 2, 3, 4}
+
+	plain, err := NewIntEncoder(data).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	withValidity, err := NewIntEncoder(data).WithValidity([]bool{true, true, true, true}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	if !slices.Equal(plain, withValidity) {
+		t.Error("expected a series without nulls to encode as if no validity were given")
+	}
+
+	values, valid, err := NewDecoder(plain).DecodeNullableInt()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !slices.Equal(values, data) || !slices.Equal(valid, []bool{true, true, true, true}) {
+		t.Errorf("expected %v all present, got %v %v", data, values, valid)
+	}
+}
+
+func TestEncoder_ValidityLengthMismatch(t *testing.T) {
+	if _, err := NewFloatEncoder([]float64{1, 2, 3}).WithValidity([]bool{true}).Encode(); err == nil {
+		t.Error("expected error for float validity length mismatch, got nil")
+	}
+	if _, err := NewIntEncoder([]int64{1, 2, 3}).WithValidity([]bool{true}).Encode(); err == nil {
+		t.Error("expected error for int validity length mismatch, got nil")
+	}
+}
+
+func TestDecoder_DenseAPIsRejectNulls(t *testing.T) {
+	encoded, err := NewFloatEncoder([]float64{1.5, 0, 2.5}).WithValidity([]bool{true, false, true}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	d := NewDecoder(encoded)
+	if _, err := d.DecodeFloat(); !errors.Is(err, errNullable) {
+		t.Errorf("DecodeFloat: expected errNullable, got %v", err)
+	}
+	if _, err := d.DecodeFloatRange(0, 1); !errors.Is(err, errNullable) {
+		t.Errorf("DecodeFloatRange: expected errNullable, got %v", err)
+	}
+	if _, err := d.At(0); !errors.Is(err, errNullable) {
+		t.Errorf("At: expected errNullable, got %v", err)
+	}
+
+	series, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if series.Kind != KindFloat || !slices.Equal(series.Valid, []bool{true, false, true}) || !slices.Equal(series.Floats, []float64{1.5, 0, 2.5}) {
+		t.Errorf("unexpected series %+v", series)
+	}
+
+	if err := Verify(encoded); err != nil {
+		t.Errorf("verify error: %v", err)
+	}
+
+	info, err := Inspect(encoded)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	if info.ValueCount != 3 || info.NullCount != 1 {
+		t.Errorf("expected 3 values with 1 null, got %d with %d", info.ValueCount, info.NullCount)
+	}
+}
+
+func TestDecoder_RejectsInconsistentValidity(t *testing.T) {
+	encoded, err := NewIntEncoder([]int64{5, 0, 7, 9}).WithValidity([]bool{true, false, true, true}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Rewrite the validity section to mark every value present, so it
+	// disagrees with the three values the header counts
+	header, size, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	_, n, err := internal.UnmarshalValidity(encoded[size:], header.ValueCount)
+	if err != nil {
+		t.Fatalf("unmarshal validity error: %v", err)
+	}
+	corrupt := append([]byte(nil), encoded[:size]...)
+	corrupt = append(corrupt, internal.MarshalValidity([]bool{true, true, true, true})...)
+	corrupt = append(corrupt, encoded[size+n:]...)
+
+	if header.ValueCount != 3 {
+		t.Fatalf("expected header to count 3 present values, got %d", header.ValueCount)
+	}
+	if _, _, err := NewDecoder(corrupt).DecodeNullableInt(); err == nil {
+		t.Error("expected error for inconsistent validity, got nil")
+	}
+}
+
+func TestDecoder_RejectsHugeValidityRuns(t *testing.T) {
+	encoded, err := NewIntEncoder([]int64{5, 0, 7}).WithValidity([]bool{true, false, true}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Swap in a validity section whose single run claims 2^60 present values
+	_, size, err := internal.Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	corrupt := append([]byte(nil), encoded[:size]...)
+	corrupt = binary.AppendUvarint(corrupt, 1<<60)
+	corrupt = append(corrupt, 0)
+	corrupt = binary.AppendUvarint(corrupt, 1)
+	corrupt = binary.AppendUvarint(corrupt, 1<<60)
+
+	if _, _, err := NewDecoder(corrupt).DecodeNullableInt(); err == nil {
+		t.Error("expected error for huge validity run, got nil")
+	}
+	if err := Verify(corrupt); err == nil {
+		t.Error("expected verify error for huge validity run, got nil")
+	}
+}
@@ -0,0 +1,138 @@
+package alpine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// Kind identifies the element type of an encoded series
+type Kind int
+
+const (
+	// KindFloat is a float64 series, produced by FloatEncoder
+	KindFloat Kind = iota
+
+	// KindInt is an int64 series, produced by IntEncoder
+	KindInt
+
+	// KindTimeSeries pairs int64 timestamps with float64 values, produced by
+	// TimeSeriesEncoder
+	KindTimeSeries
+
+	// KindFrame holds named columns, produced by FrameEncoder. Its columns are
+	// decoded through Decoder.Column.
+	KindFrame
+)
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindFloat:
+		return "float"
+	case KindInt:
+		return "int"
+	case KindTimeSeries:
+		return "timeseries"
+	case KindFrame:
+		return "frame"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Series is a decoded series of any kind. Exactly one of Floats and Ints is
+// set, as indicated by Kind, except for KindTimeSeries where Ints holds the
+// timestamps and Floats the values.
+type Series struct {
+	Kind   Kind
+	Floats []float64
+	Ints   []int64
+
+	// Valid reports which values are present; nil when the series has no nulls.
+	// Null values hold 0.
+	Valid []bool
+}
+
+// Len returns the number of values in the series
+func (s Series) Len() int {
+	if s.Kind == KindInt {
+		return len(s.Ints)
+	}
+	return len(s.Floats)
+}
+
+// Kind reports whether the encoded data holds float64 values, int64 values, a
+// time series or a frame, without decoding them
+func (d *Decoder) Kind() (Kind, error) {
+	_, blocks, err := d.blockIndex()
+	if err != nil {
+		return 0, err
+	}
+
+	// Blocks of one blob are always of the same kind
+	switch mode := blocks[0].Header.Mode; {
+	case mode.IsFloat():
+		return KindFloat, nil
+	case mode.IsInt():
+		return KindInt, nil
+	case mode == internal.ModeTimeSeries:
+		return KindTimeSeries, nil
+	case mode == internal.ModeFrame:
+		return KindFrame, nil
+	default:
+		return 0, fmt.Errorf("unknown mode %v", mode)
+	}
+}
+
+// Decode decodes the encoded data as whichever kind it holds, so callers don't
+// need to track whether a blob came from FloatEncoder or IntEncoder
+func (d *Decoder) Decode() (Series, error) {
+	kind, err := d.Kind()
+	if err != nil {
+		return Series{}, err
+	}
+
+	if kind == KindFrame {
+		return Series{}, errors.New("frame columns are decoded one at a time: use Column")
+	}
+
+	if kind == KindTimeSeries {
+		timestamps, values, err := d.DecodeTimeSeries()
+		if err != nil {
+			return Series{}, err
+		}
+		return Series{Kind: KindTimeSeries, Ints: timestamps, Floats: values}, nil
+	}
+
+	if d.valid != nil {
+		if kind == KindInt {
+			ints, valid, err := d.DecodeNullableInt()
+			if err != nil {
+				return Series{}, err
+			}
+			return Series{Kind: KindInt, Ints: ints, Valid: valid}, nil
+		}
+
+		floats, valid, err := d.DecodeNullableFloat()
+		if err != nil {
+			return Series{}, err
+		}
+		return Series{Kind: KindFloat, Floats: floats, Valid: valid}, nil
+	}
+
+	if kind == KindInt {
+		ints, err := d.DecodeInt()
+		if err != nil {
+			return Series{}, err
+		}
+		return Series{Kind: KindInt, Ints: ints}, nil
+	}
+
+	floats, err := d.DecodeFloat()
+	if err != nil {
+		return Series{}, err
+	}
+	return Series{Kind: KindFloat, Floats: floats}, nil
+}
@@ -0,0 +1,84 @@
+package alpine
+
+import (
+	"testing"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	floats := []float64{21.35, 21.40, 21.38, 21.41}
+	ints := []int64{1700000000, 1700000060, 1700000120}
+
+	encodedFloats, err := NewFloatEncoder(floats).Encode()
+	if err != nil {
+		t.Fatalf("float encode error: %v", err)
+	}
+	encodedInts, err := NewIntEncoder(ints).Encode()
+	if err != nil {
+		t.Fatalf("int encode error: %v", err)
+	}
+
+	series, err := NewDecoder(encodedFloats).Decode()
+	if err != nil {
+		t.Fatalf("float decode error: %v", err)
+	}
+	if series.Kind != KindFloat || series.Ints != nil || series.Len() != len(floats) {
+		t.Fatalf("expected %d floats, got %+v", len(floats), series)
+	}
+	for i := range floats {
+		if series.Floats[i] != floats[i] {
+			t.Errorf("floats[%d]: expected %v, got %v", i, floats[i], series.Floats[i])
+		}
+	}
+
+	series, err = NewDecoder(encodedInts).Decode()
+	if err != nil {
+		t.Fatalf("int decode error: %v", err)
+	}
+	if series.Kind != KindInt || series.Floats != nil || series.Len() != len(ints) {
+		t.Fatalf("expected %d ints, got %+v", len(ints), series)
+	}
+	for i := range ints {
+		if series.Ints[i] != ints[i] {
+			t.Errorf("ints[%d]: expected %d, got %d", i, ints[i], series.Ints[i])
+		}
+	}
+}
+
+func TestDecoder_Kind(t *testing.T) {
+	tests := []struct {
+		name    string
+		encode  func() ([]byte, error)
+		want    Kind
+		wantStr string
+	}{
+		{"float", NewFloatEncoder([]float64{1.5, 2.5, 3.5}).Encode, KindFloat, "float"},
+		{"xor float", NewFloatEncoder([]float64{1e-20, 1e300, 0.1 + 0.2}).Encode, KindFloat, "float"},
+		{"empty float", NewFloatEncoder(nil).Encode, KindFloat, "float"},
+		{"int", NewIntEncoder([]int64{1, 2, 3}).Encode, KindInt, "int"},
+		{"blocked int", NewIntEncoder(make([]int64, 100)).WithBlockSize(10).Encode, KindInt, "int"},
+		{"blocked float", NewFloatEncoder(make([]float64, 100)).WithBlockSize(10).Encode, KindFloat, "float"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.encode()
+			if err != nil {
+				t.Fatalf("encode error: %v", err)
+			}
+
+			kind, err := NewDecoder(encoded).Kind()
+			if err != nil {
+				t.Fatalf("kind error: %v", err)
+			}
+			if kind != tt.want || kind.String() != tt.wantStr {
+				t.Errorf("expected %v, got %v", tt.want, kind)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_Invalid(t *testing.T) {
+	if _, err := NewDecoder([]byte("garbage")).Decode(); err == nil {
+		t.Error("expected error for invalid data, got nil")
+	}
+}
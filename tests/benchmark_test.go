@@ -69,3 +69,69 @@ func BenchmarkDecode_10K(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkAt_1M(b *testing.B) {
+	data := generateTimeSeries(1000000)
+	encoded, err := alpine.NewFloatEncoder(data).Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	decoder := alpine.NewDecoder(encoded)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := decoder.At((i * 7919) % len(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeFloatRange_1M(b *testing.B) {
+	data := generateTimeSeries(1000000)
+	encoded, err := alpine.NewFloatEncoder(data).Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	decoder := alpine.NewDecoder(encoded)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := decoder.DecodeFloatRange(250000, 260000)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode_10K_Rice(b *testing.B) {
+	data := generateTimeSeries(10000)
+	encoded, err := alpine.NewFloatEncoder(data).Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := alpine.NewDecoder(encoded).DecodeFloat()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode_10K_FastLanes(b *testing.B) {
+	data := generateTimeSeries(10000)
+	encoded, err := alpine.NewFloatEncoder(data).WithFastLanes().Encode()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := alpine.NewDecoder(encoded).DecodeFloat()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package alpine_test
+
+import (
+	"testing"
+
+	"github.com/ach968/alpine"
+)
+
+func TestChecksum_RoundTrip(t *testing.T) {
+	data := generateTimeSeries(3000)
+
+	encoded, err := alpine.NewFloatEncoder(data).WithChecksum().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if err := alpine.Verify(encoded); err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+
+	decoded, err := alpine.Decode(encoded)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	for i := range data {
+		if decoded[i] != data[i] {
+			t.Fatalf("round-trip[%d]: expected %v, got %v", i, data[i], decoded[i])
+		}
+	}
+}
+
+func TestChecksum_DetectsCorruption(t *testing.T) {
+	ints := []int64{1700000000, 1700000060, 1700000120, 1700000181, 1700000240}
+
+	encoded, err := alpine.NewIntEncoder(ints).WithChecksum().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Flip one bit in every byte after the signature in turn
+	for i := 4; i < len(encoded); i++ {
+		corrupt := append([]byte(nil), encoded...)
+		corrupt[i] ^= 0x10
+
+		if err := alpine.Verify(corrupt); err == nil {
+			t.Errorf("byte %d: Verify accepted corrupt data", i)
+		}
+		if _, err := alpine.NewDecoder(corrupt).DecodeInt(); err == nil {
+			t.Errorf("byte %d: DecodeInt accepted corrupt data", i)
+		}
+	}
+}
+
+func TestVerify_WithoutChecksum(t *testing.T) {
+	encoded, err := alpine.NewFloatEncoder(generateTimeSeries(3000)).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if err := alpine.Verify(encoded); err != nil {
+		t.Errorf("verify error: %v", err)
+	}
+
+	// Structural damage is still caught without a checksum
+	if err := alpine.Verify(encoded[:len(encoded)/2]); err == nil {
+		t.Error("expected error for truncated blob, got nil")
+	}
+
+	if err := alpine.Verify([]byte("not alpine")); err == nil {
+		t.Error("expected error for foreign data, got nil")
+	}
+}
@@ -0,0 +1,208 @@
+package alpine_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ach968/alpine"
+)
+
+func TestAt_MatchesFullDecode(t *testing.T) {
+	data := generateTimeSeries(10000)
+
+	encoded, err := alpine.NewFloatEncoder(data).WithBlockSize(512).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoder := alpine.NewDecoder(encoded)
+	for _, i := range []int{0, 1, 2, 511, 512, 513, 5000, 9727, 9998, 9999} {
+		value, err := decoder.At(i)
+		if err != nil {
+			t.Fatalf("At(%d): %v", i, err)
+		}
+		if value != data[i] {
+			t.Errorf("At(%d): expected %v, got %v", i, data[i], value)
+		}
+	}
+}
+
+func TestAt_SingleSegment(t *testing.T) {
+	data := []float64{1.5, 2.25, 3.125, 4.0}
+
+	encoded, err := alpine.NewFloatEncoder(data).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoder := alpine.NewDecoder(encoded)
+	for i := range data {
+		value, err := decoder.At(i)
+		if err != nil {
+			t.Fatalf("At(%d): %v", i, err)
+		}
+		if value != data[i] {
+			t.Errorf("At(%d): expected %v, got %v", i, data[i], value)
+		}
+	}
+}
+
+func TestIntAt_MatchesInput(t *testing.T) {
+	data := make([]int64, 5000)
+	for i := range data {
+		data[i] = 1700000000 + int64(i)*60 + int64(i%3)
+	}
+
+	encoded, err := alpine.NewIntEncoder(data).WithBlockSize(256).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoder := alpine.NewDecoder(encoded)
+	for i := 0; i < len(data); i += 37 {
+		value, err := decoder.IntAt(i)
+		if err != nil {
+			t.Fatalf("IntAt(%d): %v", i, err)
+		}
+		if value != data[i] {
+			t.Errorf("IntAt(%d): expected %d, got %d", i, data[i], value)
+		}
+	}
+}
+
+func TestDecoder_ConcurrentAccess(t *testing.T) {
+	data := generateTimeSeries(4096)
+	encoded, err := alpine.NewFloatEncoder(data).WithBlockSize(256).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	frame, err := alpine.NewFrameEncoder().
+		AddFloat("value", alpine.NewFloatEncoder(data)).
+		Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Goroutines sharing a fresh Decoder race to fill its caches; run with -race
+	for round := range 20 {
+		decoder := alpine.NewDecoder(encoded)
+		frameDecoder := alpine.NewDecoder(frame)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for g := range 8 {
+			i := (round*8 + g) * 23 % len(data)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if value, err := decoder.At(i); err != nil || value != data[i] {
+					t.Errorf("At(%d): expected %v, got %v (%v)", i, data[i], value, err)
+				}
+				column, err := frameDecoder.Column("value")
+				if err != nil {
+					t.Errorf("column error: %v", err)
+					return
+				}
+				if value, err := column.At(i); err != nil || value != data[i] {
+					t.Errorf("column At(%d): expected %v, got %v (%v)", i, data[i], value, err)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+	}
+}
+
+func TestAt_OutOfRange(t *testing.T) {
+	encoded, err := alpine.NewFloatEncoder([]float64{1, 2, 3}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoder := alpine.NewDecoder(encoded)
+	for _, i := range []int{-1, 3} {
+		if _, err := decoder.At(i); err == nil {
+			t.Errorf("At(%d): expected error, got nil", i)
+		}
+	}
+}
+
+func TestIntAt_RejectsFloat(t *testing.T) {
+	encoded, err := alpine.NewFloatEncoder([]float64{1.5, 2.5, 3.5}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if _, err := alpine.NewDecoder(encoded).IntAt(0); err == nil {
+		t.Error("expected error reading float data as int, got nil")
+	}
+}
+
+func TestDecodeFloatRange(t *testing.T) {
+	data := generateTimeSeries(10000)
+
+	encoded, err := alpine.NewFloatEncoder(data).WithBlockSize(1000).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoder := alpine.NewDecoder(encoded)
+	ranges := [][2]int{{0, 10000}, {2500, 2600}, {999, 3001}, {9990, 10000}, {4000, 4000}, {0, 1}}
+	for _, r := range ranges {
+		values, err := decoder.DecodeFloatRange(r[0], r[1])
+		if err != nil {
+			t.Fatalf("range %v: %v", r, err)
+		}
+
+		want := data[r[0]:r[1]]
+		if len(values) != len(want) {
+			t.Fatalf("range %v: expected %d values, got %d", r, len(want), len(values))
+		}
+		for i := range want {
+			if values[i] != want[i] {
+				t.Errorf("range %v [%d]: expected %v, got %v", r, i, want[i], values[i])
+			}
+		}
+	}
+}
+
+func TestDecodeIntRange(t *testing.T) {
+	data := make([]int64, 3000)
+	for i := range data {
+		data[i] = int64(i*i) % 977
+	}
+
+	encoded, err := alpine.NewIntEncoder(data).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	values, err := alpine.NewDecoder(encoded).DecodeIntRange(1000, 2100)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	want := data[1000:2100]
+	if len(values) != len(want) {
+		t.Fatalf("expected %d values, got %d", len(want), len(values))
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("[%d]: expected %d, got %d", i, want[i], values[i])
+		}
+	}
+}
+
+func TestDecodeFloatRange_OutOfBounds(t *testing.T) {
+	encoded, err := alpine.NewFloatEncoder([]float64{1, 2, 3}).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoder := alpine.NewDecoder(encoded)
+	for _, r := range [][2]int{{-1, 2}, {2, 1}, {0, 4}} {
+		if _, err := decoder.DecodeFloatRange(r[0], r[1]); err == nil {
+			t.Errorf("range %v: expected error, got nil", r)
+		}
+	}
+}
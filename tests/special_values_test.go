@@ -0,0 +1,119 @@
+package alpine_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ach968/alpine"
+)
+
+var specialValues = []struct {
+	name string
+	bits uint64
+}{
+	{"quiet NaN", math.Float64bits(math.NaN())},
+	{"stale marker", 0x7FF0000000000002},
+	{"signalling NaN", 0x7FF0000000000001},
+	{"signalling NaN max payload", 0x7FF7FFFFFFFFFFFF},
+	{"negative quiet NaN", 0xFFF8000000000000},
+	{"NaN with payload", 0x7FF8DEADBEEF0001},
+	{"+Inf", math.Float64bits(math.Inf(1))},
+	{"-Inf", math.Float64bits(math.Inf(-1))},
+	{"-0.0", math.Float64bits(math.Copysign(0, -1))},
+	{"+0.0", 0},
+	{"smallest denormal", 1},
+	{"negative denormal", 0x800000000000000F},
+	{"max float", math.Float64bits(math.MaxFloat64)},
+}
+
+func assertBitExact(t *testing.T, input, decoded []float64) {
+	t.Helper()
+	if len(decoded) != len(input) {
+		t.Fatalf("length mismatch: expected %d, got %d", len(input), len(decoded))
+	}
+	for i := range input {
+		if math.Float64bits(decoded[i]) != math.Float64bits(input[i]) {
+			t.Errorf("round-trip[%d]: expected %#016x, got %#016x", i, math.Float64bits(input[i]), math.Float64bits(decoded[i]))
+		}
+	}
+}
+
+func TestSpecialValues_RoundTrip(t *testing.T) {
+	encoders := []struct {
+		name   string
+		encode func([]float64) ([]byte, error)
+	}{
+		{"auto", func(data []float64) ([]byte, error) { return alpine.NewFloatEncoder(data).Encode() }},
+		{"precision 2", func(data []float64) ([]byte, error) { return alpine.NewFloatEncoder(data).WithPrecision(2).Encode() }},
+		{"alp-rd", func(data []float64) ([]byte, error) { return alpine.NewFloatEncoder(data).WithALPRD().Encode() }},
+	}
+
+	for _, enc := range encoders {
+		for _, sv := range specialValues {
+			t.Run(enc.name+"/"+sv.name, func(t *testing.T) {
+				special := math.Float64frombits(sv.bits)
+				// Special value at the start, in the middle and at the end
+				input := []float64{special, 10.25, 10.5, special, 10.75, 11.0, special}
+
+				encoded, err := enc.encode(input)
+				if err != nil {
+					t.Fatalf("encode error: %v", err)
+				}
+
+				decoded, err := alpine.NewDecoder(encoded).DecodeFloat()
+				if err != nil {
+					t.Fatalf("decode error: %v", err)
+				}
+
+				assertBitExact(t, input, decoded)
+			})
+		}
+	}
+}
+
+func TestSpecialValues_AllSpecial(t *testing.T) {
+	input := make([]float64, len(specialValues))
+	for i, sv := range specialValues {
+		input[i] = math.Float64frombits(sv.bits)
+	}
+
+	encoded, err := alpine.NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoded, err := alpine.NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	assertBitExact(t, input, decoded)
+}
+
+func TestSpecialValues_StaleMarkersStayCompact(t *testing.T) {
+	// Counter-like data with periodic stale markers
+	input := make([]float64, 1000)
+	for i := range input {
+		input[i] = float64(i) * 0.5
+		if i%100 == 99 {
+			input[i] = math.Float64frombits(0x7FF0000000000002)
+		}
+	}
+
+	encoded, err := alpine.NewFloatEncoder(input).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	// Stale markers are out-of-band and must not disturb the delta predictor
+	if len(encoded) > 400 {
+		t.Errorf("expected compact encoding, got %d bytes", len(encoded))
+	}
+
+	decoded, err := alpine.NewDecoder(encoded).DecodeFloat()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	assertBitExact(t, input, decoded)
+}
@@ -0,0 +1,110 @@
+package alpine
+
+import (
+	"fmt"
+
+	"github.com/ach968/alpine/internal"
+)
+
+// TimeSeriesEncoder is a builder for encoding paired timestamps and values
+// into one blob. Timestamps go through the int pipeline and values through the
+// float pipeline, sharing a single header and value count.
+type TimeSeriesEncoder struct {
+	timestamps *IntEncoder
+	values     *FloatEncoder
+	checksum   bool
+}
+
+// NewTimeSeriesEncoder creates a new TimeSeriesEncoder. timestamps and values
+// must have the same length; values[i] is the value observed at timestamps[i].
+func NewTimeSeriesEncoder(timestamps []int64, values []float64) *TimeSeriesEncoder {
+	return &TimeSeriesEncoder{
+		timestamps: NewIntEncoder(timestamps),
+		values:     NewFloatEncoder(values),
+	}
+}
+
+// WithPrecision sets the ALP precision exponent of the values (0 = auto)
+func (e *TimeSeriesEncoder) WithPrecision(precision int) *TimeSeriesEncoder {
+	e.values.WithPrecision(precision)
+	return e
+}
+
+// WithBlockSize sets the number of values per block of both columns (default
+// DefaultBlockSize)
+func (e *TimeSeriesEncoder) WithBlockSize(size int) *TimeSeriesEncoder {
+	e.timestamps.WithBlockSize(size)
+	e.values.WithBlockSize(size)
+	return e
+}
+
+// WithChecksum appends a CRC32C checksum over the header and payload, which
+// the decoder and Verify check before trusting the data
+func (e *TimeSeriesEncoder) WithChecksum() *TimeSeriesEncoder {
+	e.checksum = true
+	return e
+}
+
+// Encode compresses both columns and returns the encoded bytes
+func (e *TimeSeriesEncoder) Encode() ([]byte, error) {
+	n := len(e.timestamps.data)
+	if len(e.values.data) != n {
+		return nil, fmt.Errorf("%d timestamps for %d values", n, len(e.values.data))
+	}
+
+	timestampHeader, timestampPayload, err := e.timestamps.encode(e.timestamps.data)
+	if err != nil {
+		return nil, fmt.Errorf("timestamps: %w", err)
+	}
+
+	valueHeader, valuePayload, err := e.values.encode(e.values.data)
+	if err != nil {
+		return nil, fmt.Errorf("values: %w", err)
+	}
+
+	header := &internal.Header{
+		Mode:       internal.ModeTimeSeries,
+		ValueCount: n,
+	}
+	payload := internal.MarshalColumns(
+		[]*internal.Header{timestampHeader, valueHeader},
+		[][]byte{timestampPayload, valuePayload},
+	)
+
+	return marshalBlob(header, payload, e.checksum), nil
+}
+
+// Column positions of a ModeTimeSeries blob
+const (
+	timestampColumn = 0
+	valueColumn     = 1
+)
+
+// DecodeTimeSeries decodes a blob produced by TimeSeriesEncoder and returns
+// its timestamps and values
+func (d *Decoder) DecodeTimeSeries() ([]int64, []float64, error) {
+	header, _, err := d.blockIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Mode != internal.ModeTimeSeries {
+		return nil, nil, fmt.Errorf("expected ModeTimeSeries, got %v", Mode(header.Mode))
+	}
+
+	columns, _, err := d.columnIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timestamps, err := columns[timestampColumn].DecodeInt()
+	if err != nil {
+		return nil, nil, fmt.Errorf("timestamps: %w", err)
+	}
+
+	values, err := columns[valueColumn].DecodeFloat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("values: %w", err)
+	}
+
+	return timestamps, values, nil
+}
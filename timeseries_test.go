@@ -0,0 +1,119 @@
+package alpine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ach968/alpine/internal"
+)
+
+func TestTimeSeriesEncoder_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		blockSize int
+	}{
+		{"empty", 0, DefaultBlockSize},
+		{"single", 1, DefaultBlockSize},
+		{"short", 60, DefaultBlockSize},
+		{"blocked", 500, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamps := make([]int64, tt.n)
+			values := make([]float64, tt.n)
+			for i := range tt.n {
+				timestamps[i] = 1700000000 + int64(i)*60
+				values[i] = 21.5 + float64(i%17)*0.05
+			}
+
+			encoded, err := NewTimeSeriesEncoder(timestamps, values).WithBlockSize(tt.blockSize).WithChecksum().Encode()
+			if err != nil {
+				t.Fatalf("encode error: %v", err)
+			}
+			if mode := blobMode(t, encoded); mode != internal.ModeTimeSeries {
+				t.Errorf("expected ModeTimeSeries, got %v", mode)
+			}
+
+			gotTimestamps, gotValues, err := NewDecoder(encoded).DecodeTimeSeries()
+			if err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if !slices.Equal(gotTimestamps, timestamps) {
+				t.Errorf("timestamps: expected %v, got %v", timestamps, gotTimestamps)
+			}
+			if !slices.Equal(gotValues, values) {
+				t.Errorf("values: expected %v, got %v", values, gotValues)
+			}
+
+			if err := Verify(encoded); err != nil {
+				t.Errorf("verify error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTimeSeriesEncoder_SmallerThanSeparateBlobs(t *testing.T) {
+	timestamps := []int64{1700000000, 1700000060, 1700000120, 1700000180, 1700000240}
+	values := []float64{21.35, 21.40, 21.38, 21.41, 21.45}
+
+	combined, err := NewTimeSeriesEncoder(timestamps, values).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	encodedTimestamps, _ := NewIntEncoder(timestamps).Encode()
+	encodedValues, _ := NewFloatEncoder(values).Encode()
+
+	if separate := len(encodedTimestamps) + len(encodedValues); len(combined) >= separate {
+		t.Errorf("expected one blob smaller than %d bytes for two, got %d", separate, len(combined))
+	}
+}
+
+func TestTimeSeriesEncoder_LengthMismatch(t *testing.T) {
+	if _, err := NewTimeSeriesEncoder([]int64{1, 2, 3}, []float64{1.5}).Encode(); err == nil {
+		t.Error("expected error for mismatched lengths, got nil")
+	}
+}
+
+func TestDecoder_TimeSeries(t *testing.T) {
+	timestamps := []int64{10, 20, 30}
+	values := []float64{1.5, 2.5, 3.5}
+
+	encoded, err := NewTimeSeriesEncoder(timestamps, values).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	d := NewDecoder(encoded)
+	series, err := d.Decode()
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if series.Kind != KindTimeSeries || series.Len() != 3 || !slices.Equal(series.Ints, timestamps) || !slices.Equal(series.Floats, values) {
+		t.Errorf("unexpected series %+v", series)
+	}
+	if series.Kind.String() != "timeseries" {
+		t.Errorf("expected kind name timeseries, got %q", series.Kind)
+	}
+
+	if _, err := d.DecodeFloat(); err == nil {
+		t.Error("expected DecodeFloat to reject a time series, got nil")
+	}
+
+	plain, _ := NewFloatEncoder(values).Encode()
+	if _, _, err := NewDecoder(plain).DecodeTimeSeries(); err == nil {
+		t.Error("expected DecodeTimeSeries to reject a float blob, got nil")
+	}
+
+	info, err := Inspect(encoded)
+	if err != nil {
+		t.Fatalf("inspect error: %v", err)
+	}
+	if info.Kind != KindTimeSeries || info.ValueCount != 3 || len(info.Columns) != 2 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if info.Columns[0].Kind != KindInt || info.Columns[1].Kind != KindFloat {
+		t.Errorf("expected int and float columns, got %v and %v", info.Columns[0].Kind, info.Columns[1].Kind)
+	}
+}